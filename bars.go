@@ -0,0 +1,111 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"time"
+
+	"github.com/jakeschurch/goat/internal/bars"
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/instruments"
+)
+
+// Bar is an OHLC summary of the quotes of a security over a period.
+type Bar = bars.Bar
+
+// BarAlgorithm is an Algorithm that is also passed bars built from quotes.
+// Bars are built as set by the simulation's config: time bars every BarRate,
+// or tick, volume or dollar bars of BarSize.
+type BarAlgorithm interface {
+	Algorithm
+	OnBar(Bar) (*Order, bool)
+}
+
+// BarsOnly can be embedded by a BarAlgorithm that only trades on bars.
+// It never creates orders from quotes.
+type BarsOnly struct{}
+
+func (BarsOnly) Buy(instruments.Quote) (*Order, bool) {
+	return nil, false
+}
+
+func (BarsOnly) Sell(instruments.Quote, *instruments.Holding) (*Order, bool) {
+	return nil, false
+}
+
+// defaultBarRate is the length of time bars if no BarRate is configured.
+const defaultBarRate = time.Minute
+
+// newBarBuilder returns a bar builder as set in a config.
+func newBarBuilder(c config.Config) *bars.Builder {
+	var source = bars.Mid
+	switch c.Simulation.BarPrice {
+	case "bid":
+		source = bars.Bid
+	case "ask":
+		source = bars.Ask
+	}
+
+	switch c.Simulation.BarType {
+	case "tick":
+		return bars.NewTick(int(c.Simulation.BarSize), source)
+	case "volume":
+		return bars.NewVolume(instruments.NewVolume(c.Simulation.BarSize), source)
+	case "dollar":
+		return bars.NewDollar(instruments.NewAmount(instruments.NewPrice(c.Simulation.BarSize), 1), source)
+	default:
+		var rate = c.Simulation.BarRate
+		if rate <= 0 {
+			rate = defaultBarRate
+		}
+		return bars.NewTime(rate, source)
+	}
+}
+
+// onBars passes closed bars to the simulation's bar algorithms,
+// placing any orders they create once the warm-up period has passed.
+func (sim *Simulation) onBars(closed []Bar) {
+	if !sim.warm.done {
+		sim.warm.count(closed)
+	}
+	for _, bar := range closed {
+		for _, algo := range sim.algos {
+			var barAlgo, ok = asBarAlgorithm(algo)
+			if !ok {
+				continue
+			}
+			if order, ok := barAlgo.OnBar(bar); ok && sim.warm.done {
+				sim.orders.Listen(order, algo)
+				sim.orders.Add(order)
+			}
+		}
+	}
+}
+
+// asBarAlgorithm returns algo as a BarAlgorithm,
+// looking through the adapter of a HandledAlgorithm.
+func asBarAlgorithm(algo Algorithm) (BarAlgorithm, bool) {
+	if h, ok := algo.(handled); ok {
+		algo = h.Algorithm
+	}
+	barAlgo, ok := algo.(BarAlgorithm)
+	return barAlgo, ok
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/instruments"
+)
+
+// barAlgo buys a share on every bar that closes up.
+type barAlgo struct {
+	BarsOnly
+	bars []Bar
+}
+
+func (algo *barAlgo) OnBar(bar Bar) (*Order, bool) {
+	algo.bars = append(algo.bars, bar)
+	if bar.Close <= bar.Open {
+		return nil, false
+	}
+	return NewOrder(bar.Name, true, instruments.Market, bar.Close, 1, bar.End), true
+}
+
+func TestSimulation_onBars(t *testing.T) {
+	var conf config.Config
+	conf.Backtest.StartCashAmt = 1000
+	conf.Simulation.BarType, conf.Simulation.BarSize = "tick", 2
+
+	algo := new(barAlgo)
+	sim := NewSim(conf, algo)
+	for _, q := range []instruments.Quote{
+		mockQuote("AAPL", 10.00, 10.02),
+		mockQuote("AAPL", 10.10, 10.12),
+		mockQuote("AAPL", 10.20, 10.22),
+	} {
+		sim.process(&q)
+	}
+	if len(algo.bars) != 1 {
+		t.Fatalf("BarAlgorithm.OnBar() called with %d bars, want 1", len(algo.bars))
+	}
+	if got, want := algo.bars[0].Quotes, 2; got != want {
+		t.Errorf("Bar.Quotes = %v, want %v", got, want)
+	}
+	if _, err := sim.port.Holdings.Get("AAPL"); err != nil {
+		t.Errorf("BarAlgorithm.OnBar() order was not filled: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jakeschurch/goat/internal/zstd"
+)
+
+// Compression is how a quote file is compressed.
+type Compression int
+
+const (
+	Uncompressed Compression = iota // 0
+	Gzip
+	Zstd
+	Bzip2
+)
+
+var magic = []struct {
+	bytes       []byte
+	compression Compression
+}{
+	{[]byte{0x1f, 0x8b}, Gzip},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, Zstd},
+	{[]byte("BZh"), Bzip2},
+}
+
+// detectCompression identifies the compression of a file from its first bytes,
+// or failing that from its extension: .gz, .zst or .bz2.
+func detectCompression(name string, head []byte) Compression {
+	for _, m := range magic {
+		if bytes.HasPrefix(head, m.bytes) {
+			return m.compression
+		}
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz", ".gzip":
+		return Gzip
+	case ".zst", ".zstd":
+		return Zstd
+	case ".bz2":
+		return Bzip2
+	}
+	return Uncompressed
+}
+
+// readCloser reads from a decompressed stream, and closes the file beneath it.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openQuotes opens a file for reading, decompressing it as it is read
+// if it is compressed with gzip, zstd or bzip2.
+// Compressed files are streamed, and are never read more than once.
+func openQuotes(name string) (io.ReadCloser, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	var buf = bufio.NewReader(file)
+	// A short file is left for its reader to reject.
+	head, _ := buf.Peek(4)
+
+	var r io.Reader = buf
+	switch detectCompression(name, head) {
+	case Gzip:
+		if r, err = gzip.NewReader(buf); err != nil {
+			file.Close()
+			return nil, err
+		}
+	case Zstd:
+		r = zstd.NewReader(buf)
+	case Bzip2:
+		r = bzip2.NewReader(buf)
+	}
+	return readCloser{r, file}, nil
+}
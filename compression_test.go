@@ -0,0 +1,110 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		head []byte
+		want Compression
+	}{
+		{"plain", "testQuotes_20170814", []byte("Time"), Uncompressed},
+		{"gzip magic", "testQuotes_20170814", []byte{0x1f, 0x8b, 0x08, 0x00}, Gzip},
+		{"zstd magic", "quotes.bin", []byte{0x28, 0xb5, 0x2f, 0xfd}, Zstd},
+		{"bzip2 magic", "quotes", []byte("BZh9"), Bzip2},
+		{"gzip extension", "testQuotes_20170814.GZ", nil, Gzip},
+		{"zstd extension", "testQuotes_20170814.zst", []byte("Ti"), Zstd},
+		{"bzip2 extension", "testQuotes_20170814.bz2", nil, Bzip2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectCompression(tt.file, tt.head); got != tt.want {
+				t.Errorf("detectCompression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The files in testdata are compressed copies of example/testQuotes_20170814.
+func TestOpenQuotes(t *testing.T) {
+	want, err := ioutil.ReadFile("example/testQuotes_20170814")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{"plain", "example/testQuotes_20170814", false},
+		{"gzip", "testdata/testQuotes_20170814.gz", false},
+		{"zstd", "testdata/testQuotes_20170814.zst", false},
+		{"bzip2", "testdata/testQuotes_20170814.bz2", false},
+		{"missing", "testdata/noSuchQuotes_20170814.gz", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := openQuotes(tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("openQuotes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer r.Close()
+			if got, err := ioutil.ReadAll(r); err != nil || !bytes.Equal(got, want) {
+				t.Errorf("openQuotes() read %d bytes, %v, want %d bytes", len(got), err, len(want))
+			}
+		})
+	}
+}
+
+func TestSimulation_Run_compressed(t *testing.T) {
+	var run = func(glob string) (int, error) {
+		var buf bytes.Buffer
+		conf := ReadConfig("example/config.json")
+		conf.File.Glob = glob
+		conf.Simulation.EquityQuotes = 1
+
+		sim := NewSim(conf, Algorithm_Example{})
+		sim.SetSink(WriterSink(&buf))
+		err := sim.Run()
+		return len(sim.perfLog.EquityCurve()), err
+	}
+	want, err := run("example/testQuotes_20170814")
+	if err != nil || want < 2 {
+		t.Fatalf("Simulation.Run() equity samples = %v, %v, want quotes to be read", want, err)
+	}
+	for _, ext := range []string{".gz", ".zst", ".bz2"} {
+		t.Run(ext, func(t *testing.T) {
+			if got, err := run("testdata/testQuotes_20170814" + ext); err != nil || got != want {
+				t.Errorf("Simulation.Run() equity samples = %v, %v, want %v", got, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"math"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/instruments"
+)
+
+// SlippageModel is used to price fills away from the quoted price.
+type SlippageModel interface {
+	// Slip returns the price a fill of volume at a quoted price is transacted at.
+	Slip(buy bool, price instruments.Price, volume instruments.Volume) instruments.Price
+}
+
+// CommissionModel is used to charge fees for transacting.
+type CommissionModel interface {
+	// Commission returns the fee charged for a transaction filling order.
+	Commission(order *Order, tx *Transaction) instruments.Amount
+}
+
+// OrderCommissionModel is a CommissionModel that keeps track of orders
+// across fills, such as to charge a minimum fee once per order.
+type OrderCommissionModel interface {
+	CommissionModel
+	// Estimate returns the fee Commission would charge for a transaction,
+	// without charging it.
+	Estimate(order *Order, tx *Transaction) instruments.Amount
+	// Done forgets an order once it will not be filled any further.
+	Done(order *Order)
+}
+
+// NewSlippageModel returns the slippage model named in a config.
+// Backtest.Slippage is passed to the model as its rate;
+// models default to BpsSlippage.
+func NewSlippageModel(c config.Config) SlippageModel {
+	switch c.Backtest.SlippageModel {
+	case "perShare":
+		return PerShareSlippage(c.Backtest.Slippage)
+	default:
+		return BpsSlippage(c.Backtest.Slippage)
+	}
+}
+
+// NewCommissionModel returns the commission model named in a config.
+// Backtest.Commission is passed to the model as its rate;
+// models default to PerShareCommission.
+func NewCommissionModel(c config.Config) CommissionModel {
+	switch c.Backtest.CommissionModel {
+	case "percent":
+		return PercentCommission(c.Backtest.Commission)
+	case "tiered":
+		var tiers = make([]CommissionTier, len(c.Backtest.CommissionTiers))
+		for i, tier := range c.Backtest.CommissionTiers {
+			tiers[i] = CommissionTier{UpTo: instruments.NewVolume(tier.UpTo), PerShare: tier.PerShare}
+		}
+		return NewTieredCommission(c.Backtest.CommissionMin, tiers...)
+	default:
+		return PerShareCommission(c.Backtest.Commission)
+	}
+}
+
+// ----------------------------------------------------------------------------
+
+// BpsSlippage moves fills away from the quoted price by a number of basis points.
+type BpsSlippage float64
+
+func (bps BpsSlippage) Slip(buy bool, price instruments.Price, volume instruments.Volume) instruments.Price {
+	return slip(buy, price, instruments.Price(math.Round(float64(price)*float64(bps)/10000)))
+}
+
+// PerShareSlippage moves fills away from the quoted price by a dollar amount.
+type PerShareSlippage float64
+
+func (amt PerShareSlippage) Slip(buy bool, price instruments.Price, volume instruments.Volume) instruments.Price {
+	return slip(buy, price, instruments.NewPrice(float64(amt)))
+}
+
+// slip price by delta against the side transacting.
+func slip(buy bool, price, delta instruments.Price) instruments.Price {
+	if buy {
+		return price + delta
+	}
+	return price - delta
+}
+
+// ----------------------------------------------------------------------------
+
+// PerShareCommission charges a fixed dollar amount for every share transacted.
+type PerShareCommission float64
+
+func (rate PerShareCommission) Commission(order *Order, tx *Transaction) instruments.Amount {
+	return perShare(float64(rate), tx.Volume)
+}
+
+// PercentCommission charges a percent of the amount transacted,
+// given as a fraction of 1.
+type PercentCommission float64
+
+func (pct PercentCommission) Commission(order *Order, tx *Transaction) instruments.Amount {
+	var amt = instruments.NewAmount(tx.Price, tx.Volume)
+	return instruments.Amount(math.Round(float64(amt) * float64(pct)))
+}
+
+// CommissionTier is a per share rate charged until UpTo shares have been transacted.
+// A tier with an UpTo of zero has no limit.
+type CommissionTier struct {
+	UpTo     instruments.Volume
+	PerShare float64
+}
+
+// TieredCommission charges a per share rate that falls as more shares are
+// transacted over the course of a simulation, with a minimum fee per order.
+type TieredCommission struct {
+	Tiers   []CommissionTier
+	Minimum float64
+
+	transacted instruments.Volume
+	// owed and charged track fees for orders still being filled,
+	// so the minimum is charged once per order rather than once per fill.
+	owed, charged map[*Order]instruments.Amount
+}
+
+func NewTieredCommission(minimum float64, tiers ...CommissionTier) *TieredCommission {
+	return &TieredCommission{
+		Tiers:   tiers,
+		Minimum: minimum,
+		owed:    make(map[*Order]instruments.Amount),
+		charged: make(map[*Order]instruments.Amount),
+	}
+}
+
+func (tc *TieredCommission) Commission(order *Order, tx *Transaction) instruments.Amount {
+	owed, total, fee := tc.fee(order, tx)
+	tc.transacted += tx.Volume
+	tc.owed[order], tc.charged[order] = owed, total
+
+	if order.Remaining() == 0 {
+		tc.Done(order)
+	}
+	return fee
+}
+
+func (tc *TieredCommission) Estimate(order *Order, tx *Transaction) instruments.Amount {
+	_, _, fee := tc.fee(order, tx)
+	return fee
+}
+
+func (tc *TieredCommission) Done(order *Order) {
+	delete(tc.owed, order)
+	delete(tc.charged, order)
+}
+
+// fee returns what is owed on an order once tx is charged, the total
+// charged for it after the minimum fee, and the fee charged for tx.
+func (tc *TieredCommission) fee(order *Order, tx *Transaction) (owed, total, fee instruments.Amount) {
+	var rate float64
+	for _, tier := range tc.Tiers {
+		rate = tier.PerShare
+		if tier.UpTo == 0 || tc.transacted < tier.UpTo {
+			break
+		}
+	}
+	owed = tc.owed[order] + perShare(rate, tx.Volume)
+
+	total = owed
+	if minimum := instruments.NewAmount(instruments.NewPrice(tc.Minimum), 1); total < minimum {
+		total = minimum
+	}
+	return owed, total, total - tc.charged[order]
+}
+
+// perShare returns the amount owed at a dollar rate per share.
+func perShare(rate float64, volume instruments.Volume) instruments.Amount {
+	return instruments.Amount(math.Round(rate * 100 * float64(volume)))
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+func TestSlippageModels(t *testing.T) {
+	tests := []struct {
+		name  string
+		model SlippageModel
+		buy   bool
+		price instruments.Price
+		want  instruments.Price
+	}{
+		{"bps buy", BpsSlippage(10), true, instruments.NewPrice(100.00), instruments.NewPrice(100.10)},
+		{"bps sell", BpsSlippage(10), false, instruments.NewPrice(100.00), instruments.NewPrice(99.90)},
+		{"per share buy", PerShareSlippage(0.02), true, instruments.NewPrice(10.00), instruments.NewPrice(10.02)},
+		{"per share sell", PerShareSlippage(0.02), false, instruments.NewPrice(10.00), instruments.NewPrice(9.98)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.model.Slip(tt.buy, tt.price, 100); got != tt.want {
+				t.Errorf("SlippageModel.Slip() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommissionModels(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	var tx = func(order *Order, volume instruments.Volume) *Transaction {
+		return order.Transact(instruments.NewPrice(10.00), volume, ts)
+	}
+	tiered := NewTieredCommission(1.00,
+		CommissionTier{UpTo: 300, PerShare: 0.01},
+		CommissionTier{UpTo: 0, PerShare: 0.005},
+	)
+	small := NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 60, ts)
+	large := NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 1000, ts)
+
+	tests := []struct {
+		name  string
+		model CommissionModel
+		order *Order
+		tx    *Transaction
+		want  instruments.Amount
+	}{
+		{"per share", PerShareCommission(0.005), small, tx(small, 1000), 500},
+		{"percent", PercentCommission(0.001), small, tx(small, 100), 100},
+		{"tiered minimum", tiered, small, tx(small, 20), 100},
+		{"tiered minimum already charged", tiered, small, tx(small, 40), 0},
+		{"tiered first tier", tiered, large, tx(large, 400), 400},
+		{"tiered second tier", tiered, large, tx(large, 600), 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.model.Commission(tt.order, tt.tx); got != tt.want {
+				t.Errorf("CommissionModel.Commission() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderManager_costs(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	om := mockOrderManager(1000)
+	om.slippage = PerShareSlippage(0.01)
+	om.commission = PerShareCommission(0.01)
+
+	txs, err := om.Buy(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 10, ts), instruments.NewPrice(10.00), 10)
+	if err != nil {
+		t.Fatalf("OrderManager.Buy() error = %v", err)
+	}
+	if txs[0].Price != instruments.NewPrice(10.01) || txs[0].Slippage != 10 || txs[0].Commission != 10 {
+		t.Errorf("OrderManager.Buy() tx = %+v", txs[0])
+	}
+	if want := instruments.NewAmount(instruments.NewPrice(1.00), 1000) - 10010 - 10; om.port.cash != want {
+		t.Errorf("OrderManager.Buy() cash = %v, want %v", om.port.cash, want)
+	}
+}
+
+func TestOrderManager_Buy_commission(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		commission CommissionModel
+		wantErr    error
+	}{
+		{"no commission", PerShareCommission(0), nil},
+		{"commission over cash", PerShareCommission(0.01), ErrLowCash},
+		{"minimum over cash", NewTieredCommission(1.00), ErrLowCash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(100)
+			om.commission = tt.commission
+			order := NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 10, ts)
+			if _, err := om.Buy(order, instruments.NewPrice(10.00), 10); err != tt.wantErr {
+				t.Errorf("OrderManager.Buy() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrderManager_CancelAll_tieredCommission(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	tiered := NewTieredCommission(1.00, CommissionTier{PerShare: 0.005})
+
+	om := mockOrderManager(10000)
+	om.commission = tiered
+	order := NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(10.00), 150, ts)
+	om.Add(order)
+	om.Match(mockQuote("AAPL", 9.95, 10.00))
+
+	if order.Status != PartiallyFilled || len(tiered.owed) != 1 {
+		t.Fatalf("OrderManager.Match() status = %v, orders owing = %v, want %v, 1", order.Status, len(tiered.owed), PartiallyFilled)
+	}
+	om.CancelAll()
+	if len(tiered.owed) != 0 || len(tiered.charged) != 0 {
+		t.Errorf("OrderManager.CancelAll() orders owing = %v, charged = %v, want none", len(tiered.owed), len(tiered.charged))
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"errors"
+
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+var (
+	ErrHalted       = errors.New("security is halted")
+	ErrInvalidPrice = errors.New("order has an invalid price")
+)
+
+// ExecutionListener can be implemented by an Algorithm
+// to be told how the orders it creates are executed.
+type ExecutionListener interface {
+	// OnFill is called with each transaction that fills part or all of an order.
+	OnFill(tx *Transaction)
+	// OnReject is called when an order cannot be filled.
+	OnReject(order *Order, reason RejectReason)
+	// OnCancel is called when a resting order is cancelled.
+	OnCancel(order *Order)
+}
+
+// RejectReason is why an order could not be filled.
+type RejectReason int
+
+const (
+	InsufficientCash RejectReason = iota
+	InsufficientVolume
+	Halted
+	RiskLimit
+	InvalidPrice
+)
+
+func (r RejectReason) String() string {
+	switch r {
+	case InsufficientCash:
+		return "insufficient cash"
+	case InsufficientVolume:
+		return "insufficient volume"
+	case Halted:
+		return "halted"
+	case RiskLimit:
+		return "risk limit"
+	case InvalidPrice:
+		return "invalid price"
+	}
+	return "unknown"
+}
+
+// rejectReason returns the reason an order was rejected with err.
+// Errors looking up holdings to sell off are reported as InsufficientVolume.
+func rejectReason(err error) RejectReason {
+	switch err {
+	case ErrLowCash:
+		return InsufficientCash
+	case ErrHalted:
+		return Halted
+	case ErrInvalidPrice, instruments.ErrZeroValue:
+		return InvalidPrice
+	}
+	if _, ok := err.(*MarginError); ok {
+		return RiskLimit
+	}
+	return InsufficientVolume
+}
+
+// validPrice reports whether an order's prices can be transacted at.
+func validPrice(order *Order) bool {
+	switch order.Logic {
+	case instruments.Limit:
+		return order.Price > 0
+	case Stop:
+		return order.Stop > 0
+	case StopLimit:
+		return order.Price > 0 && order.Stop > 0
+	case TrailingStop:
+		return order.Trail >= 0 && order.TrailPct >= 0 && (order.Trail > 0 || order.TrailPct > 0)
+	}
+	return order.Price >= 0
+}
+
+// ----------------------------------------------------------------------------
+
+// Listen records the algorithm that created an order. The algorithm is told
+// how the order is executed if it is an ExecutionListener,
+// and can manage the order through its handle if it is a HandledAlgorithm.
+func (o *OrderManager) Listen(order *Order, algo Algorithm) {
+	if h, ok := algo.(handled); ok {
+		h.handle.orders = append(h.handle.orders, order)
+	}
+	if algo != nil {
+		o.owners[order] = algo
+	}
+}
+
+// listener returns the ExecutionListener that created an order, if any.
+func (o *OrderManager) listener(order *Order) (ExecutionListener, bool) {
+	var algo = o.owners[order]
+	if h, ok := algo.(handled); ok {
+		algo = h.Algorithm
+	}
+	l, ok := algo.(ExecutionListener)
+	return l, ok
+}
+
+// Halt trading in a security. New orders for it are rejected,
+// and resting orders are not matched until trading is resumed.
+func (o *OrderManager) Halt(name string) {
+	o.halted[name] = struct{}{}
+}
+
+// Resume trading in a halted security.
+func (o *OrderManager) Resume(name string) {
+	delete(o.halted, name)
+}
+
+// reject an order that cannot be filled because of err.
+func (o *OrderManager) reject(order *Order, err error) {
+	order.Status = instruments.Cancelled
+	if l, ok := o.listener(order); ok {
+		l.OnReject(order, rejectReason(err))
+	}
+	o.done(order)
+}
+
+// cancel a resting order, recording action to the order audit log.
+func (o *OrderManager) cancel(order *Order, action string) {
+	if order.Status != instruments.Open && order.Status != PartiallyFilled {
+		return
+	}
+	o.Remove(order)
+	order.Status = instruments.Cancelled
+	o.log.AddOrderEvents(output.OrderEvent{
+		Timestamp: o.now(order.Name), Name: order.Name, Buy: order.Buy, Action: action,
+		Price: order.Price, Volume: order.Remaining(),
+	})
+	if l, ok := o.listener(order); ok {
+		l.OnCancel(order)
+	}
+	o.done(order)
+}
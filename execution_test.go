@@ -0,0 +1,113 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+// listeningAlgo records the executions reported to it.
+type listeningAlgo struct {
+	Algorithm_Example
+	fills     []*Transaction
+	rejects   []RejectReason
+	cancelled []*Order
+}
+
+func (algo *listeningAlgo) OnFill(tx *Transaction) {
+	algo.fills = append(algo.fills, tx)
+}
+
+func (algo *listeningAlgo) OnReject(order *Order, reason RejectReason) {
+	algo.rejects = append(algo.rejects, reason)
+}
+
+func (algo *listeningAlgo) OnCancel(order *Order) {
+	algo.cancelled = append(algo.cancelled, order)
+}
+
+func TestOrderManager_Listen(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	var market = func(buy bool, price float64, volume instruments.Volume) *Order {
+		return NewOrder("AAPL", buy, instruments.Market, instruments.NewPrice(price), volume, ts)
+	}
+	tests := []struct {
+		name        string
+		setup       func(om *OrderManager)
+		order       *Order
+		wantFills   int
+		wantRejects []RejectReason
+	}{
+		{"filled", nil, market(true, 10, 10), 1, nil},
+		{"insufficient cash", nil, market(true, 10, 1000), 0, []RejectReason{InsufficientCash}},
+		{"insufficient volume", nil, market(false, 10, 10), 0, []RejectReason{InsufficientVolume}},
+		{"halted", func(om *OrderManager) { om.Halt("AAPL") }, market(true, 10, 10), 0, []RejectReason{Halted}},
+		{"resumed", func(om *OrderManager) { om.Halt("AAPL"); om.Resume("AAPL") }, market(true, 10, 10), 1, nil},
+		{"risk limit", func(om *OrderManager) { om.margin = RegT() }, market(true, 10, 250), 0, []RejectReason{RiskLimit}},
+		{"invalid price", nil, NewOrder("AAPL", true, instruments.Limit, 0, 10, ts), 0, []RejectReason{InvalidPrice}},
+		{"stop without stop price", nil, NewStopOrder("AAPL", true, 0, 10, ts), 0, []RejectReason{InvalidPrice}},
+		{"trailing stop without trail", nil, NewTrailingStopOrder("AAPL", false, 0, 10, ts), 0, []RejectReason{InvalidPrice}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			if tt.setup != nil {
+				tt.setup(om)
+			}
+			algo := new(listeningAlgo)
+			om.Listen(tt.order, algo)
+			om.Add(tt.order)
+
+			if len(algo.fills) != tt.wantFills {
+				t.Errorf("ExecutionListener.OnFill() called %v times, want %v", len(algo.fills), tt.wantFills)
+			}
+			if len(algo.rejects) != len(tt.wantRejects) {
+				t.Fatalf("ExecutionListener.OnReject() reasons = %v, want %v", algo.rejects, tt.wantRejects)
+			}
+			for i := range tt.wantRejects {
+				if algo.rejects[i] != tt.wantRejects[i] {
+					t.Errorf("ExecutionListener.OnReject() reasons = %v, want %v", algo.rejects, tt.wantRejects)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderManager_Listen_cancel(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+
+	om := mockOrderManager(1000)
+	algo := new(listeningAlgo)
+	order := NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(9), 10, ts)
+	om.Listen(order, algo)
+	om.Add(order)
+	om.CancelAll()
+
+	if len(algo.cancelled) != 1 || algo.cancelled[0] != order {
+		t.Errorf("ExecutionListener.OnCancel() orders = %v, want %v", algo.cancelled, order)
+	}
+	if order.Status != instruments.Cancelled {
+		t.Errorf("OrderManager.CancelAll() status = %v, want %v", order.Status, instruments.Cancelled)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+// link an order to its OCO group, placing the other orders in the group.
+// Orders in a group are reduced by the volume any of the others fill,
+// and cancelled once nothing is left of them.
+func (o *OrderManager) link(order *Order) {
+	if _, ok := o.groups[order]; ok || len(order.OCO) == 0 {
+		return
+	}
+	var members = append([]*Order{order}, order.OCO...)
+	for _, member := range members {
+		var others = make([]*Order, 0, len(members)-1)
+		for _, other := range members {
+			if other != member {
+				others = append(others, other)
+			}
+		}
+		o.groups[member] = others
+	}
+	for _, other := range order.OCO {
+		if algo, ok := o.owners[order]; ok {
+			o.Listen(other, algo)
+		}
+		o.Add(other)
+	}
+}
+
+// reduce the orders linked to an order by the volume it filled.
+func (o *OrderManager) reduce(order *Order, volume instruments.Volume) {
+	for _, other := range o.groups[order] {
+		if other.Status != instruments.Open && other.Status != PartiallyFilled {
+			continue
+		}
+		if other.Remaining() > volume {
+			other.Volume -= volume
+			continue
+		}
+		o.cancel(other, output.Cancel)
+		o.prune(other.Name)
+	}
+}
+
+// done forgets an order once it will not be filled any further,
+// placing the orders attached to it for the volume it, and any
+// orders it replaced, filled.
+func (o *OrderManager) done(order *Order) {
+	var attached = order.Attached
+	order.Attached = nil
+
+	if filled := order.Filled() + order.replaced; filled > 0 {
+		for _, child := range attached {
+			if child.Volume > filled {
+				child.Volume = filled
+			}
+		}
+		for _, child := range attached {
+			// Children linked to a sibling were placed along with it.
+			if _, ok := o.groups[child]; ok || child.Status != instruments.Open {
+				continue
+			}
+			if algo, ok := o.owners[order]; ok {
+				o.Listen(child, algo)
+			}
+			o.Add(child)
+		}
+	}
+	if oc, ok := o.commission.(OrderCommissionModel); ok {
+		oc.Done(order)
+	}
+	for lot, working := range o.selling {
+		if working == order {
+			delete(o.selling, lot)
+		}
+	}
+	delete(o.groups, order)
+	delete(o.named, order)
+	delete(o.owners, order)
+}
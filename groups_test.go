@@ -0,0 +1,129 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+func TestOrderManager_Add_bracket(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	var sized = func(bid, ask float64, bidSz, askSz float64) instruments.Quote {
+		return instruments.Quote{
+			Name: "AAPL", Timestamp: ts,
+			Bid: instruments.NewQuotedMetric(bid, bidSz), Ask: instruments.NewQuotedMetric(ask, askSz),
+		}
+	}
+	tests := []struct {
+		name        string
+		entry       *Order
+		quote       instruments.Quote
+		wantHeld    instruments.Volume
+		wantProfit  instruments.Status
+		wantLoss    instruments.Status
+		wantVolumes [2]instruments.Volume
+	}{
+		{"take profit", NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10), 10, ts),
+			sized(11.00, 11.05, 100, 100), 0, instruments.Closed, instruments.Cancelled, [2]instruments.Volume{10, 10}},
+		{"stopped out", NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10), 10, ts),
+			sized(8.95, 9.00, 100, 100), 0, instruments.Cancelled, instruments.Closed, [2]instruments.Volume{10, 10}},
+		{"take profit partially filled", NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10), 10, ts),
+			sized(11.00, 11.05, 4, 100), 6, PartiallyFilled, instruments.Open, [2]instruments.Volume{10, 6}},
+		{"entry partially filled", NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(10), 20, ts).WithTIF(IOC),
+			sized(9.95, 10.05, 100, 100), 10, instruments.Open, instruments.Open, [2]instruments.Volume{10, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			om.Match(sized(9.95, 10.00, 100, 10))
+
+			entry := NewBracketOrder(tt.entry, instruments.NewPrice(11), instruments.NewPrice(9))
+			profit, loss := entry.Attached[0], entry.Attached[1]
+			om.Add(entry)
+			om.Match(tt.quote)
+
+			if got := om.held("AAPL"); got != tt.wantHeld {
+				t.Errorf("OrderManager.Add() held = %v, want %v", got, tt.wantHeld)
+			}
+			if profit.Status != tt.wantProfit || loss.Status != tt.wantLoss {
+				t.Errorf("OrderManager.Add() statuses = %v, %v, want %v, %v", profit.Status, loss.Status, tt.wantProfit, tt.wantLoss)
+			}
+			if got := [2]instruments.Volume{profit.Volume, loss.Volume}; got != tt.wantVolumes {
+				t.Errorf("OrderManager.Add() volumes = %v, want %v", got, tt.wantVolumes)
+			}
+		})
+	}
+}
+
+func TestOrderManager_Add_oneCancelsOther(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+
+	om := mockOrderManager(1000)
+	dip := NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(9), 10, ts)
+	breakout := NewStopOrder("AAPL", true, instruments.NewPrice(11), 10, ts)
+	om.Add(OneCancelsOther(dip, breakout))
+
+	if dip.Status != instruments.Open || breakout.Status != instruments.Open {
+		t.Fatalf("OrderManager.Add() statuses = %v, %v, want both %v", dip.Status, breakout.Status, instruments.Open)
+	}
+	om.Match(mockQuote("AAPL", 8.95, 9.00))
+	if dip.Status != instruments.Closed || breakout.Status != instruments.Cancelled {
+		t.Errorf("OrderManager.Match() statuses = %v, %v, want %v, %v", dip.Status, breakout.Status, instruments.Closed, instruments.Cancelled)
+	}
+	om.Match(mockQuote("AAPL", 11.00, 11.05))
+	if got := om.held("AAPL"); got != 10 {
+		t.Errorf("OrderManager.Match() held = %v, want 10", got)
+	}
+}
+
+func TestOrderManager_Add_bracketPlacedOnce(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+
+	om := mockOrderManager(1000)
+	entry := NewBracketOrder(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10), 10, ts),
+		instruments.NewPrice(11), instruments.NewPrice(9))
+	profit, loss := entry.Attached[0], entry.Attached[1]
+	om.Add(entry)
+	om.Match(mockQuote("AAPL", 9.95, 10.00))
+
+	if sells, err := om.GetSells("AAPL"); err != nil || len(sells) != 2 {
+		t.Fatalf("OrderManager.GetSells() = %v, %v, want the take profit and stop orders", len(sells), err)
+	}
+	om.Match(mockQuote("AAPL", 8.95, 9.00))
+	om.CancelAll()
+
+	if profit.Status != instruments.Cancelled || loss.Status != instruments.Closed {
+		t.Errorf("OrderManager.CancelAll() statuses = %v, %v, want %v, %v", profit.Status, loss.Status, instruments.Cancelled, instruments.Closed)
+	}
+	var cancels int
+	for _, e := range om.log.OrderEvents() {
+		if e.Action == output.Cancel {
+			cancels++
+		}
+	}
+	if cancels != 1 {
+		t.Errorf("OrderManager.CancelAll() cancel events = %v, want 1", cancels)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+var ErrNotResting = errors.New("order is not resting")
+
+// HandledAlgorithm can be implemented by an Algorithm to manage its own resting orders.
+// A simulation calls BuyWith and SellWith in place of Buy and Sell,
+// passing a handle to the orders the algorithm has created.
+type HandledAlgorithm interface {
+	BuyWith(*OrderHandle, instruments.Quote) (*Order, bool)
+	SellWith(*OrderHandle, instruments.Quote, *instruments.Holding) (*Order, bool)
+}
+
+// handled adapts an Algorithm that is also a HandledAlgorithm,
+// passing its handle to BuyWith and SellWith.
+type handled struct {
+	Algorithm
+	with   HandledAlgorithm
+	handle *OrderHandle
+}
+
+func (h handled) Buy(quote instruments.Quote) (*Order, bool) {
+	return h.with.BuyWith(h.handle, quote)
+}
+
+func (h handled) Sell(quote instruments.Quote, holding *instruments.Holding) (*Order, bool) {
+	return h.with.SellWith(h.handle, quote, holding)
+}
+
+// OrderHandle lets an algorithm list, cancel and replace
+// the orders it has resting in an OrderManager.
+// Cancelled and replaced orders are recorded to the order audit log.
+type OrderHandle struct {
+	om     *OrderManager
+	orders []*Order
+}
+
+// NewHandle returns a handle to orders placed through the OrderManager.
+// Orders are tracked by a handle once they are passed to Listen.
+func (o *OrderManager) NewHandle() *OrderHandle {
+	return &OrderHandle{om: o, orders: make([]*Order, 0)}
+}
+
+// Open returns the handle's orders that are still resting, in the order they were placed.
+func (h *OrderHandle) Open() []*Order {
+	var open = h.orders[:0]
+	for _, order := range h.orders {
+		if order.Status == instruments.Open || order.Status == PartiallyFilled {
+			open = append(open, order)
+		}
+	}
+	h.orders = open
+	return append([]*Order(nil), open...)
+}
+
+// Cancel a resting order.
+func (h *OrderHandle) Cancel(order *Order) error {
+	if !h.resting(order) {
+		return ErrNotResting
+	}
+	h.om.cancel(order, output.Cancel)
+	h.om.prune(order.Name)
+	return nil
+}
+
+// Replace a resting order with one at a new price for a new volume.
+// The replaced order is cancelled, and the new order is returned.
+// Orders attached to the replaced order are placed once the new order is done,
+// for no more than the volume both orders have filled.
+func (h *OrderHandle) Replace(order *Order, price instruments.Price, volume instruments.Volume) (*Order, error) {
+	if !h.resting(order) {
+		return nil, ErrNotResting
+	}
+	var replacement = NewOrder(order.Name, order.Buy, order.Logic, price, volume, h.om.now(order.Name))
+	replacement.Stop, replacement.Trail, replacement.TrailPct = order.Stop, order.Trail, order.TrailPct
+	replacement.TIF, replacement.Expires = order.TIF, order.Expires
+
+	h.om.replace(order, replacement)
+	h.orders = append(h.orders, replacement)
+	h.om.Add(replacement)
+	return replacement, nil
+}
+
+func (h *OrderHandle) resting(order *Order) bool {
+	for _, open := range h.Open() {
+		if open == order {
+			return true
+		}
+	}
+	return false
+}
+
+// ----------------------------------------------------------------------------
+
+// replace a resting order, moving its listener, named lots, the lots
+// it is selling, OCO group and attached orders to its replacement.
+func (o *OrderManager) replace(order, replacement *Order) {
+	o.Remove(order)
+	order.Status = instruments.Cancelled
+
+	if algo, ok := o.owners[order]; ok {
+		o.owners[replacement] = algo
+		delete(o.owners, order)
+	}
+	if lots, ok := o.named[order]; ok {
+		o.named[replacement] = lots
+		delete(o.named, order)
+	}
+	for lot, working := range o.selling {
+		if working == order {
+			o.selling[lot] = replacement
+		}
+	}
+	// The replacement takes the order's place in its OCO group.
+	if others, ok := o.groups[order]; ok {
+		o.groups[replacement] = others
+		for _, other := range others {
+			for i := range o.groups[other] {
+				if o.groups[other][i] == order {
+					o.groups[other][i] = replacement
+				}
+			}
+		}
+		delete(o.groups, order)
+	}
+	replacement.Attached, order.Attached = order.Attached, nil
+	replacement.replaced = order.replaced + order.Filled()
+	if oc, ok := o.commission.(OrderCommissionModel); ok {
+		oc.Done(order)
+	}
+	o.log.AddOrderEvents(output.OrderEvent{
+		Timestamp: o.now(order.Name), Name: order.Name, Buy: order.Buy, Action: output.Replace,
+		Price: order.Price, Volume: order.Remaining(),
+		NewPrice: replacement.Price, NewVolume: replacement.Volume,
+	})
+}
+
+// now returns the time of the last quote seen for a security.
+func (o *OrderManager) now(name string) time.Time {
+	if quote, ok := o.quotes[name]; ok {
+		return quote.Timestamp
+	}
+	return time.Time{}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+// handledAlgo records the handle passed to it.
+type handledAlgo struct {
+	Algorithm_Example
+	handle *OrderHandle
+}
+
+func (algo *handledAlgo) BuyWith(h *OrderHandle, quote instruments.Quote) (*Order, bool) {
+	algo.handle = h
+	return nil, false
+}
+
+func (algo *handledAlgo) SellWith(h *OrderHandle, quote instruments.Quote, holding *instruments.Holding) (*Order, bool) {
+	algo.handle = h
+	return nil, false
+}
+
+func TestNewSim_handled(t *testing.T) {
+	algo := new(handledAlgo)
+	sim := NewSim(config.Config{}, algo)
+	sim.checkBuys(mockQuote("AAPL", 10, 10.05))
+
+	if algo.handle == nil || algo.handle.om != sim.orders {
+		t.Errorf("HandledAlgorithm.BuyWith() handle = %v, want a handle to the simulation's orders", algo.handle)
+	}
+}
+
+func TestOrderHandle(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	var limit = func(price float64) *Order {
+		return NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(price), 10, ts)
+	}
+	tests := []struct {
+		name       string
+		change     func(h *OrderHandle, order *Order) (*Order, error)
+		wantErr    error
+		wantStatus instruments.Status
+		wantAction string
+		wantFilled bool
+	}{
+		{"cancel", func(h *OrderHandle, order *Order) (*Order, error) {
+			return nil, h.Cancel(order)
+		}, nil, instruments.Cancelled, output.Cancel, false},
+		{"replace", func(h *OrderHandle, order *Order) (*Order, error) {
+			return h.Replace(order, instruments.NewPrice(10), 20)
+		}, nil, instruments.Cancelled, output.Replace, true},
+		{"not resting", func(h *OrderHandle, order *Order) (*Order, error) {
+			return nil, h.Cancel(limit(9))
+		}, ErrNotResting, instruments.Open, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			h := om.NewHandle()
+			order, algo := limit(9), new(handledAlgo)
+			om.Listen(order, handled{algo, algo, h})
+			om.Add(order)
+
+			if open := h.Open(); len(open) != 1 || open[0] != order {
+				t.Fatalf("OrderHandle.Open() = %v, want %v", open, order)
+			}
+			replacement, err := tt.change(h, order)
+			if err != tt.wantErr {
+				t.Fatalf("OrderHandle change error = %v, want %v", err, tt.wantErr)
+			}
+			if order.Status != tt.wantStatus {
+				t.Errorf("OrderHandle change status = %v, want %v", order.Status, tt.wantStatus)
+			}
+			if events := om.log.OrderEvents(); tt.wantAction != "" && (len(events) != 1 || events[0].Action != tt.wantAction) {
+				t.Errorf("OrderHandle change audit log = %v, want %v", events, tt.wantAction)
+			}
+
+			// A quote crossing both prices fills whichever order is resting.
+			om.Match(mockQuote("AAPL", 8.90, 8.95))
+			if filled := om.held("AAPL") > 0; filled != tt.wantFilled {
+				t.Errorf("OrderHandle change filled = %v, want %v", filled, tt.wantFilled)
+			}
+			if replacement != nil && (replacement.Status != instruments.Closed || replacement.Filled() != 20) {
+				t.Errorf("OrderHandle.Replace() order = %v, want 20 filled", replacement)
+			}
+			if open := h.Open(); len(open) != 0 {
+				t.Errorf("OrderHandle.Open() = %v, want none", open)
+			}
+		})
+	}
+}
+
+func TestOrderHandle_Replace_attached(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	om := mockOrderManager(1000)
+	h := om.NewHandle()
+	algo := new(handledAlgo)
+
+	entry := NewBracketOrder(NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(10), 10, ts), instruments.NewPrice(12), instruments.NewPrice(8))
+	om.Listen(entry, handled{algo, algo, h})
+	om.Add(entry)
+	om.Match(instruments.Quote{
+		Name: "AAPL", Timestamp: ts,
+		Bid: instruments.NewQuotedMetric(9.95, 100), Ask: instruments.NewQuotedMetric(10.00, 4),
+	})
+
+	replacement, err := h.Replace(entry, instruments.NewPrice(9), 6)
+	if err != nil {
+		t.Fatalf("OrderHandle.Replace() error = %v", err)
+	}
+	if err = h.Cancel(replacement); err != nil {
+		t.Fatalf("OrderHandle.Cancel() error = %v", err)
+	}
+	for _, child := range replacement.Attached {
+		t.Errorf("OrderHandle.Replace() child %v was not placed", child)
+	}
+	sells, err := om.GetSells("AAPL")
+	if err != nil || len(sells) != 2 {
+		t.Fatalf("OrderBook.GetSells() = %v, %v, want the take profit and stop orders", sells, err)
+	}
+	for _, child := range sells {
+		if child.Volume != 4 {
+			t.Errorf("OrderHandle.Replace() child volume = %v, want the 4 filled before the entry was replaced", child.Volume)
+		}
+	}
+}
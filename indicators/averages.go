@@ -0,0 +1,160 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package indicators
+
+import "time"
+
+// SMA is the simple moving average of closing prices over a window.
+type SMA struct {
+	w   *window
+	sum float64
+}
+
+func NewSMA(n int) *SMA {
+	return &SMA{w: newWindow(n)}
+}
+
+func (a *SMA) Update(s Sample) {
+	a.add(s.Close)
+}
+
+func (a *SMA) add(v float64) {
+	if old, ok := a.w.push(v); ok {
+		a.sum -= old
+	}
+	a.sum += v
+}
+
+func (a *SMA) Value() float64 {
+	if a.w.len() == 0 {
+		return 0
+	}
+	return a.sum / float64(a.w.len())
+}
+
+func (a *SMA) Ready() bool {
+	return a.w.full
+}
+
+// EMA is the exponential moving average of closing prices over a period.
+// It is seeded with the simple average of the first n prices.
+type EMA struct {
+	n     int
+	alpha float64
+	seen  int
+	value float64
+}
+
+func NewEMA(n int) *EMA {
+	if n < 1 {
+		n = 1
+	}
+	return &EMA{n: n, alpha: 2 / float64(n+1)}
+}
+
+func (a *EMA) Update(s Sample) {
+	a.add(s.Close)
+}
+
+func (a *EMA) add(v float64) {
+	a.seen++
+	if a.seen <= a.n {
+		a.value += (v - a.value) / float64(a.seen)
+		return
+	}
+	a.value += a.alpha * (v - a.value)
+}
+
+func (a *EMA) Value() float64 {
+	return a.value
+}
+
+func (a *EMA) Ready() bool {
+	return a.seen >= a.n
+}
+
+// WMA is the linearly weighted moving average of closing prices over a window:
+// the newest price has a weight of n, and the oldest a weight of 1.
+type WMA struct {
+	w *window
+	// sum is the sum of prices in the window, and weighted their weighted sum.
+	sum, weighted float64
+}
+
+func NewWMA(n int) *WMA {
+	return &WMA{w: newWindow(n)}
+}
+
+func (a *WMA) Update(s Sample) {
+	var old, evicted = a.w.push(s.Close)
+	if !evicted {
+		a.weighted += float64(a.w.len()) * s.Close
+		a.sum += s.Close
+		return
+	}
+	// Each price already in the window loses a weight of 1,
+	// which drops the evicted price, and the new price is weighted by n.
+	a.weighted += float64(a.w.len())*s.Close - a.sum
+	a.sum += s.Close - old
+}
+
+func (a *WMA) Value() float64 {
+	var n = float64(a.w.len())
+	if n == 0 {
+		return 0
+	}
+	return a.weighted / (n * (n + 1) / 2)
+}
+
+func (a *WMA) Ready() bool {
+	return a.w.full
+}
+
+// VWAP is the volume weighted average of typical prices, (high+low+close)/3,
+// over a session. It resets at the start of each day.
+type VWAP struct {
+	day             time.Time
+	dollars, volume float64
+}
+
+func NewVWAP() *VWAP {
+	return new(VWAP)
+}
+
+func (a *VWAP) Update(s Sample) {
+	var y, m, d = s.Time.Date()
+	if day := time.Date(y, m, d, 0, 0, 0, 0, s.Time.Location()); !day.Equal(a.day) {
+		a.day, a.dollars, a.volume = day, 0, 0
+	}
+	a.dollars += (s.High + s.Low + s.Close) / 3 * s.Volume
+	a.volume += s.Volume
+}
+
+func (a *VWAP) Value() float64 {
+	if a.volume == 0 {
+		return 0
+	}
+	return a.dollars / a.volume
+}
+
+func (a *VWAP) Ready() bool {
+	return a.volume > 0
+}
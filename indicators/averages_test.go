@@ -0,0 +1,60 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package indicators
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAverages(t *testing.T) {
+	var samples = closes(1, 2, 3, 4, 5)
+	var volumes = []Sample{
+		{Time: day, High: 10, Low: 10, Close: 10, Volume: 100},
+		{Time: day.Add(time.Hour), High: 21, Low: 18, Close: 21, Volume: 300},
+		{Time: day.Add(24 * time.Hour), High: 30, Low: 30, Close: 30, Volume: 10},
+	}
+	tests := []struct {
+		name      string
+		indicator Indicator
+		samples   []Sample
+		want      float64
+		wantReady bool
+	}{
+		{"sma warming up", NewSMA(3), samples[:2], 1.5, false},
+		{"sma", NewSMA(3), samples, 4, true},
+		{"ema warming up", NewEMA(3), samples[:2], 1.5, false},
+		{"ema", NewEMA(3), samples, 4, true},
+		{"wma warming up", NewWMA(3), samples[:2], 5.0 / 3, false},
+		{"wma", NewWMA(3), samples, 26.0 / 6, true},
+		{"vwap empty", NewVWAP(), nil, 0, false},
+		{"vwap", NewVWAP(), volumes[:2], 17.5, true},
+		{"vwap next day", NewVWAP(), volumes, 30, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := feed(tt.indicator, tt.samples)
+			if !near(got.Value(), tt.want) || got.Ready() != tt.wantReady {
+				t.Errorf("Value() = %v, ready %v, want %v, ready %v", got.Value(), got.Ready(), tt.want, tt.wantReady)
+			}
+		})
+	}
+}
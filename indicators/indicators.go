@@ -0,0 +1,176 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package indicators implements technical indicators for algorithms.
+// Indicators are streaming: each is updated one sample at a time in constant
+// time, and is not Ready until it has seen enough samples to fill its window.
+package indicators
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/bars"
+	"github.com/jakeschurch/instruments"
+)
+
+// Sample is a period of prices that indicators are updated with.
+// Prices are in dollars.
+type Sample struct {
+	Time                   time.Time
+	Open, High, Low, Close float64
+	Volume                 float64
+}
+
+// FromQuote returns a sample of a quote's mid price and average quoted size.
+// It returns false if the quote is missing a bid or an ask.
+func FromQuote(quote instruments.Quote) (Sample, bool) {
+	if quote.Bid == nil || quote.Ask == nil || quote.Bid.Price == 0 || quote.Ask.Price == 0 {
+		return Sample{}, false
+	}
+	var mid = dollars(quote.Bid.Price+quote.Ask.Price) / 2
+	return Sample{
+		Time: quote.Timestamp,
+		Open: mid, High: mid, Low: mid, Close: mid,
+		Volume: float64(quote.Bid.Volume+quote.Ask.Volume) / 2,
+	}, true
+}
+
+// FromBar returns a sample of a bar.
+func FromBar(bar bars.Bar) Sample {
+	return Sample{
+		Time: bar.End,
+		Open: dollars(bar.Open), High: dollars(bar.High), Low: dollars(bar.Low), Close: dollars(bar.Close),
+		Volume: float64(bar.Volume),
+	}
+}
+
+func dollars(p instruments.Price) float64 {
+	return float64(p) / 100
+}
+
+// Indicator is a value computed from a stream of samples.
+// Value is meaningless until the indicator is Ready.
+type Indicator interface {
+	Update(Sample)
+	Value() float64
+	Ready() bool
+}
+
+// Set keeps a separate indicator for each security.
+type Set struct {
+	new        func() Indicator
+	indicators map[string]Indicator
+	sync.Mutex
+}
+
+// NewSet returns a set that creates indicators of a security with new
+// the first time the security is sampled.
+func NewSet(new func() Indicator) *Set {
+	return &Set{new: new, indicators: make(map[string]Indicator)}
+}
+
+// Quote updates the indicator of a quoted security, returning the indicator.
+// Quotes missing a bid or an ask are skipped.
+func (s *Set) Quote(quote instruments.Quote) Indicator {
+	var i = s.get(quote.Name)
+	if sample, ok := FromQuote(quote); ok {
+		i.Update(sample)
+	}
+	return i
+}
+
+// Bar updates the indicator of a bar's security, returning the indicator.
+func (s *Set) Bar(bar bars.Bar) Indicator {
+	var i = s.get(bar.Name)
+	i.Update(FromBar(bar))
+	return i
+}
+
+// Get returns the indicator of a security, or false if it has not been sampled.
+func (s *Set) Get(name string) (Indicator, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	i, ok := s.indicators[name]
+	return i, ok
+}
+
+func (s *Set) get(name string) Indicator {
+	s.Lock()
+	defer s.Unlock()
+
+	i, ok := s.indicators[name]
+	if !ok {
+		i = s.new()
+		s.indicators[name] = i
+	}
+	return i
+}
+
+// window is a ring buffer of the last n values.
+type window struct {
+	values []float64
+	next   int
+	full   bool
+}
+
+func newWindow(n int) *window {
+	if n < 1 {
+		n = 1
+	}
+	return &window{values: make([]float64, n)}
+}
+
+// push adds a value to the window, returning the value it evicts
+// and whether a value was evicted.
+func (w *window) push(v float64) (float64, bool) {
+	var old, evicted = w.values[w.next], w.full
+	w.values[w.next] = v
+	w.next++
+	if w.next == len(w.values) {
+		w.next, w.full = 0, true
+	}
+	return old, evicted
+}
+
+// oldest returns the oldest value in the window.
+func (w *window) oldest() float64 {
+	if !w.full {
+		return w.values[0]
+	}
+	return w.values[w.next]
+}
+
+// len returns the number of values in the window.
+func (w *window) len() int {
+	if w.full {
+		return len(w.values)
+	}
+	return w.next
+}
+
+// newest returns the newest value in the window.
+func (w *window) newest() float64 {
+	if w.next == 0 {
+		return w.values[len(w.values)-1]
+	}
+	return w.values[w.next-1]
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/bars"
+	"github.com/jakeschurch/instruments"
+)
+
+var day = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+
+// closes returns a sample closing at each price, a minute apart.
+func closes(prices ...float64) []Sample {
+	var samples = make([]Sample, len(prices))
+	for i, p := range prices {
+		samples[i] = Sample{Time: day.Add(time.Duration(i) * time.Minute), Open: p, High: p, Low: p, Close: p}
+	}
+	return samples
+}
+
+// feed updates an indicator with samples, returning the indicator.
+func feed(i Indicator, samples []Sample) Indicator {
+	for _, s := range samples {
+		i.Update(s)
+	}
+	return i
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestFromQuote(t *testing.T) {
+	tests := []struct {
+		name   string
+		quote  instruments.Quote
+		want   Sample
+		wantOk bool
+	}{
+		{"mid", instruments.Quote{Name: "AAPL", Timestamp: day,
+			Bid: instruments.NewQuotedMetric(10, 100), Ask: instruments.NewQuotedMetric(10.02, 300)},
+			Sample{Time: day, Open: 10.01, High: 10.01, Low: 10.01, Close: 10.01, Volume: 200}, true},
+		{"no ask", instruments.Quote{Name: "AAPL", Timestamp: day,
+			Bid: instruments.NewQuotedMetric(10, 100)}, Sample{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FromQuote(tt.quote)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("FromQuote() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	var set = NewSet(func() Indicator { return NewSMA(2) })
+	set.Quote(instruments.Quote{Name: "AAPL", Timestamp: day,
+		Bid: instruments.NewQuotedMetric(10, 100), Ask: instruments.NewQuotedMetric(10.02, 100)})
+	set.Bar(bars.Bar{Name: "MSFT", End: day, Open: 2000, High: 2100, Low: 1900, Close: 2050, Volume: 100})
+	got := set.Bar(bars.Bar{Name: "AAPL", End: day.Add(time.Minute), Open: 1000, High: 1010, Low: 990, Close: 1003})
+
+	if !got.Ready() || !near(got.Value(), 10.02) {
+		t.Errorf("Set.Bar() = %v, ready %v, want 10.02, ready", got.Value(), got.Ready())
+	}
+	if msft, ok := set.Get("MSFT"); !ok || msft.Ready() || !near(msft.Value(), 20.5) {
+		t.Errorf("Set.Get(MSFT) = %v, %v, want 20.5 and not ready", msft, ok)
+	}
+	if _, ok := set.Get("GOOG"); ok {
+		t.Errorf("Set.Get(GOOG) found an indicator that was never sampled")
+	}
+}
+
+func TestRolling(t *testing.T) {
+	var samples = closes(5, 1, 4, 3, 6, 2)
+	tests := []struct {
+		name      string
+		indicator Indicator
+		samples   []Sample
+		want      float64
+		wantReady bool
+	}{
+		{"min warming up", NewMin(3), samples[:2], 1, false},
+		{"min", NewMin(3), samples[:4], 1, true},
+		{"min evicted", NewMin(3), samples[:5], 3, true},
+		{"max", NewMax(3), samples[:4], 4, true},
+		{"max evicted", NewMax(3), samples, 6, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := feed(tt.indicator, tt.samples)
+			if !near(got.Value(), tt.want) || got.Ready() != tt.wantReady {
+				t.Errorf("Value() = %v, ready %v, want %v, ready %v", got.Value(), got.Ready(), tt.want, tt.wantReady)
+			}
+		})
+	}
+}
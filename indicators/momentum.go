@@ -0,0 +1,142 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package indicators
+
+// RSI is the relative strength index of closing prices over a period,
+// smoothed by Wilder's method. It ranges from 0 to 100.
+type RSI struct {
+	n       int
+	changes int
+	last    float64
+	gain    float64
+	loss    float64
+	hasLast bool
+}
+
+func NewRSI(n int) *RSI {
+	if n < 1 {
+		n = 1
+	}
+	return &RSI{n: n}
+}
+
+func (r *RSI) Update(s Sample) {
+	if !r.hasLast {
+		r.last, r.hasLast = s.Close, true
+		return
+	}
+	var gain, loss float64
+	if change := s.Close - r.last; change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+	r.last = s.Close
+	r.changes++
+
+	// Average the first n changes, and smooth those after.
+	var n = float64(r.n)
+	if r.changes <= r.n {
+		n = float64(r.changes)
+	}
+	r.gain += (gain - r.gain) / n
+	r.loss += (loss - r.loss) / n
+}
+
+func (r *RSI) Value() float64 {
+	if r.loss == 0 {
+		if r.gain == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+r.gain/r.loss)
+}
+
+func (r *RSI) Ready() bool {
+	return r.changes >= r.n
+}
+
+// MACD is the difference between a fast and a slow EMA of closing prices,
+// along with a signal line that is an EMA of the difference.
+type MACD struct {
+	fast, slow, signal *EMA
+}
+
+// NewMACD returns a MACD of fast, slow and signal periods, commonly 12, 26 and 9.
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+func (m *MACD) Update(s Sample) {
+	m.fast.add(s.Close)
+	m.slow.add(s.Close)
+	if m.slow.Ready() && m.fast.Ready() {
+		m.signal.add(m.Value())
+	}
+}
+
+// Value returns the MACD line.
+func (m *MACD) Value() float64 {
+	return m.fast.Value() - m.slow.Value()
+}
+
+// Signal returns the signal line.
+func (m *MACD) Signal() float64 {
+	return m.signal.Value()
+}
+
+// Histogram returns the difference between the MACD and signal lines.
+func (m *MACD) Histogram() float64 {
+	return m.Value() - m.Signal()
+}
+
+func (m *MACD) Ready() bool {
+	return m.signal.Ready()
+}
+
+// ROC is the rate of change of closing prices over n periods, as a percent.
+type ROC struct {
+	w *window
+}
+
+func NewROC(n int) *ROC {
+	if n < 1 {
+		n = 1
+	}
+	return &ROC{w: newWindow(n + 1)}
+}
+
+func (r *ROC) Update(s Sample) {
+	r.w.push(s.Close)
+}
+
+func (r *ROC) Value() float64 {
+	var old = r.w.oldest()
+	if !r.w.full || old == 0 {
+		return 0
+	}
+	return (r.w.newest() - old) / old * 100
+}
+
+func (r *ROC) Ready() bool {
+	return r.w.full
+}
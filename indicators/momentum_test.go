@@ -0,0 +1,62 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package indicators
+
+import "testing"
+
+func TestMomentum(t *testing.T) {
+	tests := []struct {
+		name      string
+		indicator Indicator
+		samples   []Sample
+		want      float64
+		wantReady bool
+	}{
+		{"rsi warming up", NewRSI(2), closes(1, 2), 100, false},
+		{"rsi gains", NewRSI(2), closes(1, 2, 3), 100, true},
+		{"rsi", NewRSI(2), closes(1, 2, 3, 2.5), 100 - 100.0/3, true},
+		{"rsi flat", NewRSI(2), closes(1, 1, 1), 50, true},
+		{"roc warming up", NewROC(2), closes(100, 110), 0, false},
+		{"roc", NewROC(2), closes(100, 110, 121), 21, true},
+		{"macd warming up", NewMACD(2, 3, 2), closes(1, 2, 3), 0.5, false},
+		{"macd", NewMACD(2, 3, 2), closes(1, 2, 3, 4, 5, 6), 0.5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := feed(tt.indicator, tt.samples)
+			if !near(got.Value(), tt.want) || got.Ready() != tt.wantReady {
+				t.Errorf("Value() = %v, ready %v, want %v, ready %v", got.Value(), got.Ready(), tt.want, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestMACD_Signal(t *testing.T) {
+	var m = feed(NewMACD(2, 3, 2), closes(1, 2, 3, 4, 5, 6, 12)).(*MACD)
+
+	// fast: 5.5 -> 9.83, slow: 5 -> 8.5, so the MACD line moves from 0.5 to 4/3.
+	if !near(m.Value(), 4.0/3) {
+		t.Errorf("MACD.Value() = %v, want %v", m.Value(), 4.0/3)
+	}
+	if want := 0.5 + 2.0/3*(4.0/3-0.5); !near(m.Signal(), want) || !near(m.Histogram(), 4.0/3-want) {
+		t.Errorf("MACD.Signal() = %v, Histogram() = %v, want %v, %v", m.Signal(), m.Histogram(), want, 4.0/3-want)
+	}
+}
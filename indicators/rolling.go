@@ -0,0 +1,84 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package indicators
+
+// Rolling is the lowest or highest closing price over a window.
+// It keeps a deque of the prices that can still become the extreme,
+// so that each update takes constant amortized time.
+type Rolling struct {
+	n    int
+	max  bool
+	seen int
+	// deque holds the index and price of candidates in time order.
+	deque []point
+}
+
+type point struct {
+	i int
+	v float64
+}
+
+// NewMin returns the lowest closing price over a window of n.
+func NewMin(n int) *Rolling {
+	return newRolling(n, false)
+}
+
+// NewMax returns the highest closing price over a window of n.
+func NewMax(n int) *Rolling {
+	return newRolling(n, true)
+}
+
+func newRolling(n int, max bool) *Rolling {
+	if n < 1 {
+		n = 1
+	}
+	return &Rolling{n: n, max: max, deque: make([]point, 0, n)}
+}
+
+func (r *Rolling) Update(s Sample) {
+	// Candidates the new price beats can never be the extreme again.
+	for len(r.deque) > 0 && r.beats(s.Close, r.deque[len(r.deque)-1].v) {
+		r.deque = r.deque[:len(r.deque)-1]
+	}
+	r.deque = append(r.deque, point{r.seen, s.Close})
+	r.seen++
+	if r.deque[0].i <= r.seen-1-r.n {
+		r.deque = r.deque[1:]
+	}
+}
+
+func (r *Rolling) beats(v, other float64) bool {
+	if r.max {
+		return v >= other
+	}
+	return v <= other
+}
+
+func (r *Rolling) Value() float64 {
+	if len(r.deque) == 0 {
+		return 0
+	}
+	return r.deque[0].v
+}
+
+func (r *Rolling) Ready() bool {
+	return r.seen >= r.n
+}
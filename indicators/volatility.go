@@ -0,0 +1,149 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package indicators
+
+import "math"
+
+// StdDev is the population standard deviation of closing prices over a window.
+type StdDev struct {
+	w          *window
+	sum, sumSq float64
+	last       float64
+}
+
+func NewStdDev(n int) *StdDev {
+	return &StdDev{w: newWindow(n)}
+}
+
+func (d *StdDev) Update(s Sample) {
+	if old, ok := d.w.push(s.Close); ok {
+		d.sum -= old
+		d.sumSq -= old * old
+	}
+	d.sum += s.Close
+	d.sumSq += s.Close * s.Close
+	d.last = s.Close
+}
+
+// Mean returns the average closing price over the window.
+func (d *StdDev) Mean() float64 {
+	if d.w.len() == 0 {
+		return 0
+	}
+	return d.sum / float64(d.w.len())
+}
+
+func (d *StdDev) Value() float64 {
+	var n = float64(d.w.len())
+	if n == 0 {
+		return 0
+	}
+	var mean = d.sum / n
+	// Running sums can leave a variance just below zero.
+	return math.Sqrt(math.Max(d.sumSq/n-mean*mean, 0))
+}
+
+func (d *StdDev) Ready() bool {
+	return d.w.full
+}
+
+// ZScore is the number of standard deviations the last closing price
+// is from the average over a window.
+type ZScore struct {
+	StdDev
+}
+
+func NewZScore(n int) *ZScore {
+	return &ZScore{StdDev{w: newWindow(n)}}
+}
+
+func (z *ZScore) Value() float64 {
+	var sd = z.StdDev.Value()
+	if sd == 0 {
+		return 0
+	}
+	return (z.last - z.Mean()) / sd
+}
+
+// Bollinger bands are a moving average of closing prices over a window,
+// with bands k standard deviations above and below it.
+type Bollinger struct {
+	StdDev
+	k float64
+}
+
+// NewBollinger returns Bollinger bands of window n, commonly 20 and 2.
+func NewBollinger(n int, k float64) *Bollinger {
+	return &Bollinger{StdDev{w: newWindow(n)}, k}
+}
+
+// Value returns the middle band.
+func (b *Bollinger) Value() float64 {
+	return b.Mean()
+}
+
+func (b *Bollinger) Upper() float64 {
+	return b.Mean() + b.k*b.StdDev.Value()
+}
+
+func (b *Bollinger) Lower() float64 {
+	return b.Mean() - b.k*b.StdDev.Value()
+}
+
+// ATR is the average true range over a period, smoothed by Wilder's method.
+type ATR struct {
+	n       int
+	ranges  int
+	last    float64
+	hasLast bool
+	value   float64
+}
+
+func NewATR(n int) *ATR {
+	if n < 1 {
+		n = 1
+	}
+	return &ATR{n: n}
+}
+
+func (a *ATR) Update(s Sample) {
+	var tr = s.High - s.Low
+	if a.hasLast {
+		tr = math.Max(tr, math.Max(math.Abs(s.High-a.last), math.Abs(s.Low-a.last)))
+	}
+	a.last, a.hasLast = s.Close, true
+	a.ranges++
+
+	// Average the first n ranges, and smooth those after.
+	var n = float64(a.n)
+	if a.ranges <= a.n {
+		n = float64(a.ranges)
+	}
+	a.value += (tr - a.value) / n
+}
+
+func (a *ATR) Value() float64 {
+	return a.value
+}
+
+func (a *ATR) Ready() bool {
+	return a.ranges >= a.n
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVolatility(t *testing.T) {
+	var samples = closes(2, 4, 4, 4, 5, 5, 7, 9)
+	var ranges = []Sample{
+		{High: 10, Low: 8, Close: 9},
+		{High: 11, Low: 9, Close: 10},
+		{High: 14, Low: 12, Close: 13},
+	}
+	tests := []struct {
+		name      string
+		indicator Indicator
+		samples   []Sample
+		want      float64
+		wantReady bool
+	}{
+		{"stdev warming up", NewStdDev(8), samples[:2], 1, false},
+		{"stdev", NewStdDev(8), samples, 2, true},
+		{"stdev evicted", NewStdDev(4), samples, math.Sqrt(2.75), true},
+		{"zscore", NewZScore(8), samples, 2, true},
+		{"zscore flat", NewZScore(2), closes(3, 3), 0, true},
+		{"bollinger", NewBollinger(8, 2), samples, 5, true},
+		{"atr warming up", NewATR(2), ranges[:1], 2, false},
+		{"atr", NewATR(2), ranges, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := feed(tt.indicator, tt.samples)
+			if !near(got.Value(), tt.want) || got.Ready() != tt.wantReady {
+				t.Errorf("Value() = %v, ready %v, want %v, ready %v", got.Value(), got.Ready(), tt.want, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestBollinger_bands(t *testing.T) {
+	var b = feed(NewBollinger(8, 2), closes(2, 4, 4, 4, 5, 5, 7, 9)).(*Bollinger)
+	if !near(b.Upper(), 9) || !near(b.Lower(), 1) {
+		t.Errorf("Bollinger bands = %v, %v, want 9, 1", b.Upper(), b.Lower())
+	}
+}
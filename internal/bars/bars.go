@@ -0,0 +1,235 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package bars aggregates quotes into OHLC bars.
+package bars
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+// Bar is an OHLC summary of the quotes of a security over a period.
+type Bar struct {
+	Name       string
+	Start, End time.Time
+	Open       instruments.Price
+	High       instruments.Price
+	Low        instruments.Price
+	Close      instruments.Price
+	// Spread is the average quoted spread, and Quotes the number of quotes, over the bar.
+	Spread instruments.Price
+	Quotes int
+	// Volume and Dollars are the size and value quoted over the bar.
+	Volume  instruments.Volume
+	Dollars instruments.Amount
+
+	spreads instruments.Amount
+}
+
+// Source is the quoted price bars are built from.
+type Source int
+
+const (
+	Mid Source = iota // 0
+	Bid
+	Ask
+)
+
+// Kind is what closes a bar.
+type Kind int
+
+const (
+	// Time bars close at the end of each interval of a duration.
+	Time Kind = iota // 0
+	// Tick bars close after a number of quotes.
+	Tick
+	// Volume bars close once a quoted size has been reached.
+	Volume
+	// Dollar bars close once a quoted value has been reached.
+	Dollar
+)
+
+// Builder aggregates quotes into bars for each security.
+type Builder struct {
+	kind   Kind
+	source Source
+	// interval is the length of Time bars;
+	// size is the quotes, volume or dollars that close other bars.
+	interval time.Duration
+	size     int64
+
+	open map[string]*Bar
+	// due is when the first open Time bar ends.
+	due time.Time
+}
+
+// NewTime returns a Builder of bars that close every interval.
+// Intervals are aligned to the zero time, so minute bars close on the minute.
+func NewTime(interval time.Duration, source Source) *Builder {
+	return &Builder{kind: Time, source: source, interval: interval, open: make(map[string]*Bar)}
+}
+
+// NewTick returns a Builder of bars that close after n quotes.
+func NewTick(n int, source Source) *Builder {
+	return &Builder{kind: Tick, source: source, size: int64(n), open: make(map[string]*Bar)}
+}
+
+// NewVolume returns a Builder of bars that close once volume has been quoted.
+func NewVolume(volume instruments.Volume, source Source) *Builder {
+	return &Builder{kind: Volume, source: source, size: int64(volume), open: make(map[string]*Bar)}
+}
+
+// NewDollar returns a Builder of bars that close once an amount has been quoted.
+func NewDollar(amt instruments.Amount, source Source) *Builder {
+	return &Builder{kind: Dollar, source: source, size: int64(amt), open: make(map[string]*Bar)}
+}
+
+// Update a security's bar from a quote, returning any bars that were closed.
+// Quotes missing a side needed by the builder's source are skipped.
+func (b *Builder) Update(quote instruments.Quote) []Bar {
+	var closed = make([]Bar, 0, 1)
+
+	price, volume, ok := b.quoted(quote)
+	if !ok {
+		return closed
+	}
+	if b.kind == Time {
+		closed = append(closed, b.closeDue(quote.Timestamp)...)
+	}
+	bar, ok := b.open[quote.Name]
+	if !ok {
+		bar = &Bar{Name: quote.Name, Start: quote.Timestamp, Open: price, High: price, Low: price}
+		if b.kind == Time {
+			bar.Start = quote.Timestamp.Truncate(b.interval)
+			bar.End = bar.Start.Add(b.interval)
+			if b.due.IsZero() || bar.End.Before(b.due) {
+				b.due = bar.End
+			}
+		}
+		b.open[quote.Name] = bar
+	}
+
+	if price > bar.High {
+		bar.High = price
+	}
+	if price < bar.Low {
+		bar.Low = price
+	}
+	bar.Close = price
+	bar.Quotes++
+	bar.Volume += volume
+	bar.Dollars += instruments.NewAmount(price, volume)
+	if quote.Bid != nil && quote.Ask != nil {
+		bar.spreads += instruments.Amount(quote.Ask.Price - quote.Bid.Price)
+	}
+	if b.kind != Time {
+		bar.End = quote.Timestamp
+	}
+
+	var full bool
+	switch b.kind {
+	case Tick:
+		full = int64(bar.Quotes) >= b.size
+	case Volume:
+		full = int64(bar.Volume) >= b.size
+	case Dollar:
+		full = int64(bar.Dollars) >= b.size
+	}
+	if full {
+		closed = append(closed, b.close(quote.Name))
+	}
+	return closed
+}
+
+// closeDue closes the Time bars of every security that have ended by timestamp,
+// so that bars are closed on time even if a security is not quoted again.
+func (b *Builder) closeDue(timestamp time.Time) []Bar {
+	if b.due.IsZero() || timestamp.Before(b.due) {
+		return nil
+	}
+	b.due = time.Time{}
+
+	var names = make([]string, 0, len(b.open))
+	for name, bar := range b.open {
+		switch {
+		case !timestamp.Before(bar.End):
+			names = append(names, name)
+		case b.due.IsZero() || bar.End.Before(b.due):
+			b.due = bar.End
+		}
+	}
+	return b.closeAll(names)
+}
+
+// Flush closes every open bar, returning them in name order.
+func (b *Builder) Flush() []Bar {
+	var names = make([]string, 0, len(b.open))
+	for name := range b.open {
+		names = append(names, name)
+	}
+	b.due = time.Time{}
+	return b.closeAll(names)
+}
+
+func (b *Builder) closeAll(names []string) []Bar {
+	sort.Strings(names)
+
+	var closed = make([]Bar, 0, len(names))
+	for _, name := range names {
+		closed = append(closed, b.close(name))
+	}
+	return closed
+}
+
+func (b *Builder) close(name string) Bar {
+	var bar = *b.open[name]
+	delete(b.open, name)
+
+	if bar.Quotes > 0 {
+		bar.Spread = instruments.Price(bar.spreads / instruments.Amount(bar.Quotes))
+	}
+	bar.spreads = 0
+	return bar
+}
+
+// quoted returns the price and size of a quote at the builder's source.
+// Mid bars are sized at the average of the bid and ask sizes.
+func (b *Builder) quoted(quote instruments.Quote) (instruments.Price, instruments.Volume, bool) {
+	switch b.source {
+	case Bid:
+		if quote.Bid == nil || quote.Bid.Price == 0 {
+			return 0, 0, false
+		}
+		return quote.Bid.Price, quote.Bid.Volume, true
+	case Ask:
+		if quote.Ask == nil || quote.Ask.Price == 0 {
+			return 0, 0, false
+		}
+		return quote.Ask.Price, quote.Ask.Volume, true
+	default:
+		if quote.Bid == nil || quote.Ask == nil || quote.Bid.Price == 0 || quote.Ask.Price == 0 {
+			return 0, 0, false
+		}
+		return (quote.Bid.Price + quote.Ask.Price) / 2, (quote.Bid.Volume + quote.Ask.Volume) / 2, true
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bars
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+var start = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+
+func quote(name string, seconds int, bid, ask float64, size float64) instruments.Quote {
+	return instruments.Quote{
+		Name: name, Timestamp: start.Add(time.Duration(seconds) * time.Second),
+		Bid: instruments.NewQuotedMetric(bid, size), Ask: instruments.NewQuotedMetric(ask, size),
+	}
+}
+
+func TestBuilder_Update(t *testing.T) {
+	var quotes = []instruments.Quote{
+		quote("AAPL", 0, 10.00, 10.02, 100),
+		quote("AAPL", 20, 10.10, 10.14, 100),
+		quote("AAPL", 40, 9.90, 9.92, 300),
+		quote("AAPL", 61, 10.00, 10.06, 100),
+	}
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    []Bar
+	}{
+		{"time mid", NewTime(time.Minute, Mid), []Bar{
+			{Name: "AAPL", Start: start, End: start.Add(time.Minute), Open: 1001, High: 1012, Low: 991, Close: 991,
+				Spread: 2, Quotes: 3, Volume: 500, Dollars: 100100 + 101200 + 297300},
+		}},
+		{"time bid", NewTime(time.Minute, Bid), []Bar{
+			{Name: "AAPL", Start: start, End: start.Add(time.Minute), Open: 1000, High: 1010, Low: 990, Close: 990,
+				Spread: 2, Quotes: 3, Volume: 500, Dollars: 100000 + 101000 + 297000},
+		}},
+		{"tick", NewTick(2, Ask), []Bar{
+			{Name: "AAPL", Start: start, End: start.Add(20 * time.Second), Open: 1002, High: 1014, Low: 1002, Close: 1014,
+				Spread: 3, Quotes: 2, Volume: 200, Dollars: 100200 + 101400},
+			{Name: "AAPL", Start: start.Add(40 * time.Second), End: start.Add(61 * time.Second), Open: 992, High: 1006, Low: 992, Close: 1006,
+				Spread: 4, Quotes: 2, Volume: 400, Dollars: 297600 + 100600},
+		}},
+		{"volume", NewVolume(400, Bid), []Bar{
+			{Name: "AAPL", Start: start, End: start.Add(40 * time.Second), Open: 1000, High: 1010, Low: 990, Close: 990,
+				Spread: 2, Quotes: 3, Volume: 500, Dollars: 100000 + 101000 + 297000},
+		}},
+		{"dollar", NewDollar(200000, Bid), []Bar{
+			{Name: "AAPL", Start: start, End: start.Add(20 * time.Second), Open: 1000, High: 1010, Low: 1000, Close: 1010,
+				Spread: 3, Quotes: 2, Volume: 200, Dollars: 100000 + 101000},
+			{Name: "AAPL", Start: start.Add(40 * time.Second), End: start.Add(40 * time.Second), Open: 990, High: 990, Low: 990, Close: 990,
+				Spread: 2, Quotes: 1, Volume: 300, Dollars: 297000},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got = make([]Bar, 0)
+			for _, q := range quotes {
+				got = append(got, tt.builder.Update(q)...)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Builder.Update() = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Builder.Update() bar %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuilder_closeDue(t *testing.T) {
+	b := NewTime(time.Minute, Mid)
+	b.Update(quote("AAPL", 0, 10.00, 10.02, 100))
+	b.Update(quote("MSFT", 30, 20.00, 20.02, 100))
+
+	got := b.Update(quote("MSFT", 60, 20.00, 20.02, 100))
+	if len(got) != 2 || got[0].Name != "AAPL" || got[1].Name != "MSFT" {
+		t.Errorf("Builder.Update() = %+v, want AAPL and MSFT bars", got)
+	}
+	if got := b.Flush(); len(got) != 1 || got[0].Name != "MSFT" || got[0].Start != start.Add(time.Minute) {
+		t.Errorf("Builder.Flush() = %+v, want the open MSFT bar", got)
+	}
+	if got := b.Flush(); len(got) != 0 {
+		t.Errorf("Builder.Flush() = %+v, want none", got)
+	}
+}
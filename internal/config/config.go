@@ -22,12 +22,16 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
+var ErrNoFiles = errors.New("no files matched file glob")
+
 func ReadConfig(filename string) Config {
 	var conf Config
 	var file, _ = ioutil.ReadFile(filename)
@@ -42,14 +46,27 @@ type Config struct {
 		Delim         string `json:"delim,omitempty"`
 		ExampleDate   string `json:"exampleDate,omitempty"`
 		TimestampUnit string `json:"timestampUnit,omitempty"`
+		// Format is "jsonl" for JSON Lines, "binary" for goat's binary format,
+		// or empty for delimited text.
+		Format string `json:"format,omitempty"`
+		// Quoted files may quote fields as in RFC 4180, so that
+		// fields can hold delimiters, quotes and newlines.
+		Quoted bool `json:"quoted,omitempty"`
+		// Comments are prefixes of lines that are skipped, such as "#".
+		Comments []string `json:"comments,omitempty"`
+		// Parsers is the number of goroutines that parse quotes from a file.
+		// If zero, one is started for each CPU.
+		Parsers int `json:"parsers,omitempty"`
 
+		// Columns are given by position, or by header text
+		// if the file has a header row.
 		Columns struct {
-			Ticker    uint8 `json:"ticker,omitempty"`
-			Timestamp uint8 `json:"timestamp,omitempty"`
-			Bid       uint8 `json:"bid,omitempty"`
-			BidSize   uint8 `json:"bidSize,omitempty"`
-			Ask       uint8 `json:"ask,omitempty"`
-			AskSize   uint8 `json:"askSize,omitempty"`
+			Ticker    Column `json:"ticker,omitempty"`
+			Timestamp Column `json:"timestamp,omitempty"`
+			Bid       Column `json:"bid,omitempty"`
+			BidSize   Column `json:"bidSize,omitempty"`
+			Ask       Column `json:"ask,omitempty"`
+			AskSize   Column `json:"askSize,omitempty"`
 		} `json:"columns,omitempty"`
 	} `json:"file,omitempty"`
 
@@ -57,7 +74,34 @@ type Config struct {
 		StartCashAmt     float64  `json:"startCashAmt,omitempty"`
 		IgnoreSecurities []string `json:"ignoreSecurities,omitempty"`
 		Slippage         float64  `json:"slippage,omitempty"`
+		SlippageModel    string   `json:"slippageModel,omitempty"`
 		Commission       float64  `json:"commission,omitempty"`
+		CommissionModel  string   `json:"commissionModel,omitempty"`
+		CommissionMin    float64  `json:"commissionMin,omitempty"`
+		CommissionTiers  []struct {
+			UpTo     float64 `json:"upTo,omitempty"`
+			PerShare float64 `json:"perShare,omitempty"`
+		} `json:"commissionTiers,omitempty"`
+		// AllowShort lets securities that are not held be sold short.
+		// Without a margin model, shorts must be fully covered by equity.
+		AllowShort bool `json:"allowShort,omitempty"`
+		// BorrowRate is the annual fee charged on the market value
+		// of short holdings, given as a fraction of 1.
+		BorrowRate float64 `json:"borrowRate,omitempty"`
+		// HardToBorrow lists securities that cannot be sold short.
+		HardToBorrow []string `json:"hardToBorrow,omitempty"`
+		// Margin sets the terms cash is borrowed on to buy securities.
+		// If Model is empty, buys are limited by cash.
+		Margin struct {
+			// Model is "regT" for 50% initial and 25% maintenance margin,
+			// or "portfolio" for a single margin percent set by Maintenance.
+			Model string `json:"model,omitempty"`
+			// Initial and Maintenance are fractions of 1 that override the model's defaults.
+			Initial     float64 `json:"initial,omitempty"`
+			Maintenance float64 `json:"maintenance,omitempty"`
+			// InterestRate is the annual rate charged on negative cash.
+			InterestRate float64 `json:"interestRate,omitempty"`
+		} `json:"margin,omitempty"`
 	} `json:"backtest,omitempty"`
 
 	Simulation struct {
@@ -65,6 +109,32 @@ type Config struct {
 		EndDate      string        `json:"endDate,omitempty"`
 		BarRate      time.Duration `json:"barRate,omitempty"`
 		OutputFormat string        `json:"outFmt,omitempty"`
+		// OutputPath is the file results are written to;
+		// if empty or "-", results are written to stdout.
+		OutputPath string `json:"outputPath,omitempty"`
+		// EquityQuotes is the number of quotes between equity samples.
+		// If zero, equity is sampled every BarRate instead.
+		EquityQuotes int `json:"equityQuotes,omitempty"`
+		// BarType is "time", "tick", "volume" or "dollar", and defaults to time bars
+		// every BarRate. BarSize is the quotes, shares or dollars that close other bars,
+		// and BarPrice is the quoted price bars are built from: "mid", "bid" or "ask".
+		BarType  string  `json:"barType,omitempty"`
+		BarSize  float64 `json:"barSize,omitempty"`
+		BarPrice string  `json:"barPrice,omitempty"`
+		// WarmUp is a period from the first quote, and WarmUpBars a number of bars
+		// of a security, during which quotes are passed to algorithms but their
+		// orders are suppressed and equity is not recorded. If both are set,
+		// both must pass before trading starts.
+		WarmUp     time.Duration `json:"warmUp,omitempty"`
+		WarmUpBars int           `json:"warmUpBars,omitempty"`
+		// SessionClose is the time of day, as "15:04", that DAY orders expire at.
+		// If empty, sessions close at 16:00.
+		SessionClose string `json:"sessionClose,omitempty"`
+		// CostMethod selects the lots relieved when holdings are sold off:
+		// "fifo", "lifo", "hifo", "lofo", "average" or "specific". Defaults to "fifo".
+		CostMethod string `json:"costMethod,omitempty"`
+		// RiskFreeRate is the annual rate risk-adjusted returns are measured against.
+		RiskFreeRate float64 `json:"riskFreeRate,omitempty"`
 		//  IngestRate measures how many bars to skip
 		// IngestRate BarDuration `json:"ingestRate"`
 	} `json:"simulation,omitempty"`
@@ -72,9 +142,35 @@ type Config struct {
 	Benchmark struct {
 		Use    bool `json:"use,omitempty"`
 		Update bool `json:"update,omitempty"`
+		// Symbol is the security bought and held by the benchmark.
+		Symbol string `json:"symbol,omitempty"`
+		// File is an optional file of timestamp,price lines to read
+		// benchmark prices from, rather than the quote files.
+		File string `json:"file,omitempty"`
 	} `json:"benchmark,omitempty"`
 }
 
+// Column is the position of a field in a quote file, counting from 0,
+// or the header text of the field. In JSON it is either a number or a string.
+type Column struct {
+	Index uint8
+	Name  string
+}
+
+func (c *Column) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &c.Name)
+	}
+	return json.Unmarshal(b, &c.Index)
+}
+
+func (c Column) MarshalJSON() ([]byte, error) {
+	if c.Name != "" {
+		return json.Marshal(c.Name)
+	}
+	return json.Marshal(c.Index)
+}
+
 func (c Config) FileInfo() (fname string, date time.Time, err error) {
 	var fileGlob []string
 
@@ -86,8 +182,86 @@ func (c Config) FileInfo() (fname string, date time.Time, err error) {
 	fname, _ = filepath.Abs(fileGlob[0])
 
 	// parse date from file string
-	fdate := fname[strings.LastIndex(fname, "_")+1:]
-	date, err = time.Parse(c.File.ExampleDate, fdate)
+	date, err = c.fileDate(fname)
 
 	return fname, date, err
 }
+
+// DataFile is a quote file matched by File.Glob,
+// along with the date parsed from its filename.
+type DataFile struct {
+	Name string
+	Date time.Time
+}
+
+// Files returns every file matched by File.Glob in date order.
+// Files dated outside of Simulation.StartDate and Simulation.EndDate
+// are skipped; either bound is ignored if left empty.
+//
+// JSON Lines and binary quotes carry their own dates, so their files
+// need not be dated; undated files are read first, in glob order.
+func (c Config) Files() ([]DataFile, error) {
+	var files = make([]DataFile, 0)
+	var start, end time.Time
+	var err error
+
+	if c.Simulation.StartDate != "" {
+		if start, err = time.Parse(c.File.ExampleDate, c.Simulation.StartDate); err != nil {
+			return files, err
+		}
+	}
+	if c.Simulation.EndDate != "" {
+		if end, err = time.Parse(c.File.ExampleDate, c.Simulation.EndDate); err != nil {
+			return files, err
+		}
+	}
+
+	fileGlob, err := filepath.Glob(c.File.Glob)
+	if err != nil {
+		return files, err
+	}
+	for _, match := range fileGlob {
+		var fname, _ = filepath.Abs(match)
+
+		date, err := c.fileDate(fname)
+		if err != nil {
+			if c.File.Format == "" {
+				return files, err
+			}
+			files = append(files, DataFile{Name: fname})
+			continue
+		}
+		if (!start.IsZero() && date.Before(start)) || (!end.IsZero() && date.After(end)) {
+			continue
+		}
+		files = append(files, DataFile{Name: fname, Date: date})
+	}
+	if len(files) == 0 {
+		return files, ErrNoFiles
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Date.Before(files[j].Date)
+	})
+	return files, nil
+}
+
+// compressedExts are extensions of compressed files,
+// which are not part of the date suffix of a filename.
+var compressedExts = []string{".gz", ".gzip", ".zst", ".zstd", ".bz2"}
+
+// fileDate parses the date suffix of a filename using File.ExampleDate.
+// The suffix may be followed by a file extension, such as ".jsonl".
+func (c Config) fileDate(fname string) (time.Time, error) {
+	fdate := fname[strings.LastIndex(fname, "_")+1:]
+	for _, ext := range compressedExts {
+		if strings.HasSuffix(strings.ToLower(fdate), ext) {
+			fdate = fdate[:len(fdate)-len(ext)]
+			break
+		}
+	}
+	date, err := time.Parse(c.File.ExampleDate, fdate)
+	if ext := filepath.Ext(fdate); err != nil && ext != "" {
+		return time.Parse(c.File.ExampleDate, strings.TrimSuffix(fdate, ext))
+	}
+	return date, err
+}
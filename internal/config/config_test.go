@@ -21,6 +21,7 @@
 package config
 
 import (
+	"encoding/json"
 	"path/filepath"
 	"reflect"
 	"testing"
@@ -62,3 +63,70 @@ func TestConfig_FileInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_Files(t *testing.T) {
+	var fname, _ = filepath.Abs("../../example/config.json")
+	var wantedName, _ = filepath.Abs("../../example/testQuotes_20170814")
+	var wantedDate, _ = time.Parse("20060102", "20170814")
+
+	var conf = ReadConfig(fname)
+	var unbounded, afterEnd = conf, conf
+	unbounded.Simulation.StartDate, unbounded.Simulation.EndDate = "", ""
+	afterEnd.Simulation.StartDate, afterEnd.Simulation.EndDate = "20170815", ""
+
+	tests := []struct {
+		name    string
+		conf    Config
+		want    []DataFile
+		wantErr bool
+	}{
+		{"base case", conf, []DataFile{{wantedName, wantedDate}}, false},
+		{"no date bounds", unbounded, []DataFile{{wantedName, wantedDate}}, false},
+		{"file before start date", afterEnd, []DataFile{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.conf.Files()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Config.Files() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Config.Files() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumn_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Column
+		wantErr bool
+	}{
+		{"index", `16`, Column{Index: 16}, false},
+		{"name", `"Best_Bid_Price"`, Column{Name: "Best_Bid_Price"}, false},
+		{"out of range", `256`, Column{}, true},
+		{"not a column", `true`, Column{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Column
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Column.UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Column.UnmarshalJSON() = %v, want %v", got, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			if b, _ := json.Marshal(got); string(b) != tt.json {
+				t.Errorf("Column.MarshalJSON() = %s, want %s", b, tt.json)
+			}
+		})
+	}
+}
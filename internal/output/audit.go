@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package output
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+// OrderEvent is a change made to a resting order,
+// as recorded to a PerformanceLog's order audit log.
+type OrderEvent struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Name      string             `json:"name"`
+	Buy       bool               `json:"buy"`
+	Action    string             `json:"action"`
+	Price     instruments.Price  `json:"price"`
+	Volume    instruments.Volume `json:"volume"`
+	// NewPrice and NewVolume are set if the order was replaced.
+	NewPrice  instruments.Price  `json:"newPrice,omitempty"`
+	NewVolume instruments.Volume `json:"newVolume,omitempty"`
+}
+
+// Actions recorded to the order audit log.
+const (
+	Cancel  = "cancel"
+	Replace = "replace"
+	// Expire is recorded when an order is cancelled because its time in force has passed.
+	Expire = "expire"
+)
+
+func (e OrderEvent) ToSlice() []string {
+	return []string{
+		e.Timestamp.Format(time.RFC3339Nano),
+		e.Name,
+		strconv.FormatBool(e.Buy),
+		e.Action,
+		formatPrice(e.Price),
+		e.Volume.String(),
+		formatPrice(e.NewPrice),
+		e.NewVolume.String(),
+	}
+}
+
+func GetOrderEventHeaders() []string {
+	return []string{
+		"Timestamp",
+		"Name",
+		"Buy",
+		"Action",
+		"Price",
+		"Volume",
+		"New Price",
+		"New Volume",
+	}
+}
+
+// AddOrderEvents records changes made to resting orders to the order audit log.
+func (plog *PerformanceLog) AddOrderEvents(events ...OrderEvent) {
+	plog.audit = append(plog.audit, events...)
+}
+
+// OrderEvents returns the order audit log.
+func (plog *PerformanceLog) OrderEvents() []OrderEvent {
+	return plog.audit
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package output
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+// Equity is a mark-to-market sample of a portfolio's value.
+type Equity struct {
+	Timestamp     time.Time          `json:"timestamp"`
+	Cash          instruments.Amount `json:"cash"`
+	MarketValue   instruments.Amount `json:"marketValue"`
+	Equity        instruments.Amount `json:"equity"`
+	GrossExposure instruments.Amount `json:"grossExposure"`
+	NetExposure   instruments.Amount `json:"netExposure"`
+	// Benchmark is the value of the benchmark portfolio, if one is used.
+	Benchmark instruments.Amount `json:"benchmark,omitempty"`
+}
+
+func (e Equity) ToSlice() []string {
+	return []string{
+		e.Timestamp.Format(time.RFC3339Nano),
+		formatAmount(e.Cash),
+		formatAmount(e.MarketValue),
+		formatAmount(e.Equity),
+		formatAmount(e.GrossExposure),
+		formatAmount(e.NetExposure),
+		formatAmount(e.Benchmark),
+	}
+}
+
+func GetEquityHeaders() []string {
+	return []string{
+		"Timestamp",
+		"Cash",
+		"Market Value",
+		"Equity",
+		"Gross Exposure",
+		"Net Exposure",
+		"Benchmark",
+	}
+}
+
+// AddEquity records samples to a PerformanceLog's equity curve.
+func (plog *PerformanceLog) AddEquity(samples ...Equity) {
+	plog.equity = append(plog.equity, samples...)
+}
+
+// SetEquity records a sample to a PerformanceLog's equity curve,
+// replacing the last sample if it was taken at the same time.
+func (plog *PerformanceLog) SetEquity(sample Equity) {
+	if n := len(plog.equity); n > 0 && plog.equity[n-1].Timestamp.Equal(sample.Timestamp) {
+		plog.equity[n-1] = sample
+		return
+	}
+	plog.equity = append(plog.equity, sample)
+}
+
+// EquityCurve returns the equity samples recorded so far.
+func (plog *PerformanceLog) EquityCurve() []Equity {
+	return plog.equity
+}
+
+// benchmarkReturn returns the benchmark's return between two times,
+// using the last equity sample taken at or before each.
+// If no benchmark has been sampled, ok is false.
+func (plog *PerformanceLog) benchmarkReturn(from, to time.Time) (ret float64, ok bool) {
+	var valueAt = func(t time.Time) instruments.Amount {
+		i := sort.Search(len(plog.equity), func(i int) bool {
+			return plog.equity[i].Timestamp.After(t)
+		})
+		if i == 0 {
+			return 0
+		}
+		return plog.equity[i-1].Benchmark
+	}
+	start, end := valueAt(from), valueAt(to)
+	if start == 0 || end == 0 {
+		return 0, false
+	}
+	return float64(end)/float64(start) - 1, true
+}
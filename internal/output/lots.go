@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package output
+
+import (
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+// Lot is the portion of a holding relieved by a sell,
+// along with the profit or loss realized from selling it.
+type Lot struct {
+	Name        string             `json:"name"`
+	Volume      instruments.Volume `json:"volume"`
+	BuyPrice    instruments.Price  `json:"buyPrice"`
+	BuyDate     time.Time          `json:"buyDate"`
+	SellPrice   instruments.Price  `json:"sellPrice"`
+	SellDate    time.Time          `json:"sellDate"`
+	RealizedPnL instruments.Amount `json:"realizedPnL"`
+}
+
+// NewLot returns the lot of volume relieved from a holding at a sell price and date.
+func NewLot(h instruments.Holding, volume instruments.Volume, sell instruments.TxMetric) Lot {
+	return Lot{
+		Name:        h.Name,
+		Volume:      volume,
+		BuyPrice:    h.Buy.Price,
+		BuyDate:     h.Buy.Date,
+		SellPrice:   sell.Price,
+		SellDate:    sell.Date,
+		RealizedPnL: instruments.NewAmount(sell.Price-h.Buy.Price, volume),
+	}
+}
+
+func (l Lot) ToSlice() []string {
+	return []string{
+		l.Name,
+		l.Volume.String(),
+		formatPrice(l.BuyPrice),
+		l.BuyDate.Format(time.RFC3339Nano),
+		formatPrice(l.SellPrice),
+		l.SellDate.Format(time.RFC3339Nano),
+		formatAmount(l.RealizedPnL),
+	}
+}
+
+func GetLotHeaders() []string {
+	return []string{
+		"Name",
+		"Volume",
+		"Buy Price",
+		"Buy Date",
+		"Sell Price",
+		"Sell Date",
+		"Realized PnL",
+	}
+}
+
+// AddLots records lots relieved by sells to a PerformanceLog.
+func (plog *PerformanceLog) AddLots(lots ...Lot) {
+	plog.lots = append(plog.lots, lots...)
+}
+
+// Lots returns the lots relieved so far.
+func (plog *PerformanceLog) Lots() []Lot {
+	return plog.lots
+}
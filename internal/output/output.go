@@ -24,24 +24,41 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"io"
-	"log"
-	"os"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jakeschurch/collections"
+	"github.com/jakeschurch/goat/internal/stats"
 	"github.com/jakeschurch/instruments"
 )
 
-// PerformanceLog tracks closed orders and holdings.
+// PerformanceLog tracks closed orders, holdings and the lots relieved from them,
+// as well as the costs paid on transactions and an audit log of changes made to resting orders.
 type PerformanceLog struct {
 	orders   *collections.OrderBook
 	holdings *collections.Portfolio
+	costs    map[string]*costs
+	equity   []Equity
+	lots     []Lot
+	audit    []OrderEvent
+	riskFree float64
+}
+
+// costs are the totals paid transacting and borrowing a security.
+type costs struct {
+	commission, slippage, borrow instruments.Amount
 }
 
 func NewPerformanceLog() *PerformanceLog {
 	return &PerformanceLog{
 		orders:   collections.NewOrderBook(),
 		holdings: collections.NewPortfolio(),
+		costs:    make(map[string]*costs),
+		equity:   make([]Equity, 0),
+		lots:     make([]Lot, 0),
+		audit:    make([]OrderEvent, 0),
 	}
 }
 func (plog *PerformanceLog) AddOrders(orders ...*instruments.Order) {
@@ -56,52 +73,208 @@ func (plog *PerformanceLog) AddHoldings(holdings ...*instruments.Holding) {
 	}
 }
 
-func (plog *PerformanceLog) OutputResults(format Format, pathName string) {
+// Transaction represents a fulfillment of an order,
+// along with the costs paid to transact it.
+type Transaction struct {
+	*instruments.Transaction
+	// Commission is the fee paid to transact.
+	Commission instruments.Amount
+	// Slippage is the cost of transacting away from the quoted price.
+	Slippage instruments.Amount
+}
+
+// AddTransactions records the commission and slippage paid on transactions.
+func (plog *PerformanceLog) AddTransactions(txs ...*Transaction) {
+	for _, tx := range txs {
+		c, ok := plog.costs[tx.Name]
+		if !ok {
+			c = new(costs)
+			plog.costs[tx.Name] = c
+		}
+		c.commission += tx.Commission
+		c.slippage += tx.Slippage
+	}
+}
+
+// AddBorrowFees records fees paid borrowing a security to sell short.
+func (plog *PerformanceLog) AddBorrowFees(name string, fee instruments.Amount) {
+	c, ok := plog.costs[name]
+	if !ok {
+		c = new(costs)
+		plog.costs[name] = c
+	}
+	c.borrow += fee
+}
+
+// SetRiskFreeRate sets the annual rate that risk-adjusted returns are measured against.
+func (plog *PerformanceLog) SetRiskFreeRate(rate float64) {
+	plog.riskFree = rate
+}
+
+// Stats computes performance statistics from a PerformanceLog's
+// equity curve and closed holdings.
+func (plog *PerformanceLog) Stats() stats.Stats {
+	var equity = make([]stats.Point, len(plog.equity))
+	var benchmark = make([]stats.Point, 0, len(plog.equity))
+	for i, e := range plog.equity {
+		equity[i] = stats.Point{Time: e.Timestamp, Value: float64(e.Equity) / 100}
+		if e.Benchmark != 0 {
+			benchmark = append(benchmark, stats.Point{Time: e.Timestamp, Value: float64(e.Benchmark) / 100})
+		}
+	}
+
+	var trades = make([]float64, 0)
+	for key := range plog.holdings.Holdings.Keys() {
+		holdingSlice, _ := plog.holdings.GetSlice(key)
+		for _, h := range holdingSlice {
+			pnl := instruments.NewAmount(h.Sell.Price-h.Buy.Price, h.Volume)
+			trades = append(trades, float64(pnl)/100)
+		}
+	}
+	return stats.Compute(equity, benchmark, trades, plog.riskFree)
+}
+
+// OutputResults writes holding, equity, lot, order audit and statistic results to sink.
+func (plog *PerformanceLog) OutputResults(format Format, sink Sink) error {
 	var holdingResults = make([][]string, 0)
 
 	for key, _ := range plog.holdings.Holdings.Keys() {
 		holdingSlice, _ := plog.holdings.GetSlice(key)
-		holdingResults = append(holdingResults, NewHoldingSummary(holdingSlice...).ToSlice())
+		hs := NewHoldingSummary(holdingSlice...)
+		if c, ok := plog.costs[key]; ok {
+			hs.addCosts(c.commission, c.slippage, c.borrow)
+		}
+		hs.Alpha = plog.alpha(holdingSlice...)
+		holdingResults = append(holdingResults, hs.ToSlice())
 	}
-	switch format {
-	case JSON:
-		ToJSON(holdingResults, pathName)
-	default:
-		ToCSV(holdingResults, pathName)
+	var equityResults = make([][]string, len(plog.equity))
+	for i := range plog.equity {
+		equityResults[i] = plog.equity[i].ToSlice()
+	}
+
+	var lotResults = make([][]string, len(plog.lots))
+	for i := range plog.lots {
+		lotResults[i] = plog.lots[i].ToSlice()
+	}
+
+	var auditResults = make([][]string, len(plog.audit))
+	for i := range plog.audit {
+		auditResults[i] = plog.audit[i].ToSlice()
+	}
+
+	var summary = plog.Stats()
+
+	var results = []result{
+		{"holdings", GetHeaders(), holdingResults, holdingResults},
+		{"equity", GetEquityHeaders(), equityResults, plog.equity},
+		{"lots", GetLotHeaders(), lotResults, plog.lots},
+		{"audit", GetOrderEventHeaders(), auditResults, plog.audit},
+		{"stats", GetStatsHeaders(), statsToSlice(summary), summary},
+	}
+	for _, result := range results {
+		w, err := sink.Open(result.name, format)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case JSON:
+			err = ToJSON(w, result.data)
+		default:
+			err = ToCSV(w, result.headers, result.rows)
+		}
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
 	}
+	return sink.Close()
+}
+
+// result is a named set of results, as rows with headers and as data marshalled to JSON.
+type result struct {
+	name    string
+	headers []string
+	rows    [][]string
+	data    interface{}
 }
 
 // ----------------------------------------------------------------------------
 
-func ToCSV(data [][]string, pathName string) {
-	data = append([][]string{GetHeaders()}, data...)
+// ToCSV writes headers followed by rows of data to w.
+func ToCSV(w io.Writer, headers []string, data [][]string) error {
+	data = append([][]string{headers}, data...)
 
-	var file, err = os.Create(pathName)
-	if err != nil {
-		log.Fatalln(err)
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(data); err != nil {
+		return err
 	}
+	return cw.Error()
+}
 
-	w := csv.NewWriter(file)
-	for _, row := range data {
-		w.Write(row)
+// ToJSON writes data marshalled as JSON to w.
+func ToJSON(w io.Writer, data interface{}) error {
+	marshalled, err := json.Marshal(data)
+	if err != nil {
+		return err
 	}
-	w.Flush()
-	file.Close()
+	_, err = w.Write(marshalled)
+	return err
 }
 
-func ToJSON(data [][]string, pathName string) {
-	var file, err = os.Create(pathName)
-	if err != nil {
-		log.Fatalln(err)
+// alpha is the average return of holdings in excess of the
+// benchmark's return over the time each was held,
+// in hundredths of a percent.
+func (plog *PerformanceLog) alpha(holdings ...*instruments.Holding) instruments.Amount {
+	var excess float64
+	var n int
+
+	for _, h := range holdings {
+		benchReturn, ok := plog.benchmarkReturn(h.Buy.Date, h.Sell.Date)
+		if !ok || h.Buy.Price == 0 {
+			continue
+		}
+		excess += float64(h.Sell.Price)/float64(h.Buy.Price) - 1 - benchReturn
+		n++
 	}
+	if n == 0 {
+		return 0
+	}
+	return instruments.Amount(math.Round(excess / float64(n) * 10000))
+}
 
-	w := io.Writer(file)
-	marshalled, err := json.Marshal(data)
-	if err != nil {
-		log.Fatalln(err)
+func GetStatsHeaders() []string {
+	return []string{"Statistic", "Value"}
+}
+
+// statsToSlice returns a row for each statistic.
+func statsToSlice(s stats.Stats) [][]string {
+	var pct = func(f float64) string { return strconv.FormatFloat(f*100, 'f', 2, 64) + "%" }
+	var num = func(f float64) string { return strconv.FormatFloat(f, 'f', 4, 64) }
+
+	return [][]string{
+		{"Total Return", pct(s.TotalReturn)},
+		{"Annualized Return", pct(s.AnnualizedReturn)},
+		{"Volatility", pct(s.Volatility)},
+		{"Sharpe Ratio", num(s.Sharpe)},
+		{"Sortino Ratio", num(s.Sortino)},
+		{"Calmar Ratio", num(s.Calmar)},
+		{"Max. Drawdown", pct(s.MaxDrawdown)},
+		{"Max. Drawdown Duration", s.MaxDrawdownDuration.String()},
+		{"Number of Trades", strconv.Itoa(s.Trades)},
+		{"Win Rate", pct(s.WinRate)},
+		{"Profit Factor", num(s.ProfitFactor)},
+		{"Avg. Win", num(s.AvgWin)},
+		{"Avg. Loss", num(s.AvgLoss)},
+		{"Expectancy", num(s.Expectancy)},
+		{"Beta", num(s.Beta)},
+		{"Jensen's Alpha", pct(s.Alpha)},
+		{"Tracking Error", pct(s.TrackingError)},
+		{"Information Ratio", num(s.InformationRatio)},
+		{"Up Capture", pct(s.UpCapture)},
+		{"Down Capture", pct(s.DownCapture)},
 	}
-	w.Write(marshalled)
-	file.Close()
 }
 
 type Format uint
@@ -111,6 +284,16 @@ const (
 	JSON
 )
 
+// ParseFormat returns the Format named by s, defaulting to CSV.
+func ParseFormat(s string) Format {
+	switch strings.ToLower(s) {
+	case "json":
+		return JSON
+	default:
+		return CSV
+	}
+}
+
 type holdingSummary struct {
 	Name           string
 	AvgVolume      instruments.Volume   `json:"AvgVolume,omitempty"`
@@ -123,6 +306,11 @@ type holdingSummary struct {
 	NumOrderFilled uint                 `json:"NumOrderFilled,omitempty"`
 	PctReturn      instruments.Amount   `json:"pctReturn,omitempty"`
 	Alpha          instruments.Amount   `json:"alpha,omitempty"`
+	GrossPnL       instruments.Amount   `json:"grossPnL,omitempty"`
+	Commission     instruments.Amount   `json:"commission,omitempty"`
+	Slippage       instruments.Amount   `json:"slippage,omitempty"`
+	BorrowFees     instruments.Amount   `json:"borrowFees,omitempty"`
+	NetPnL         instruments.Amount   `json:"netPnL,omitempty"`
 }
 
 func (hs *holdingSummary) ToSlice() []string {
@@ -139,11 +327,41 @@ func (hs *holdingSummary) ToSlice() []string {
 		hs.MaxBid.Date.Format(time.RFC1123),
 		hs.MinBid.Price.String(),
 		hs.MinBid.Date.Format(time.RFC1123),
-		string(hs.NumOrderFilled),
-		hs.PctReturn.ToPercent(),
-		hs.Alpha.ToPercent(),
+		strconv.FormatUint(uint64(hs.NumOrderFilled), 10),
+		formatPercent(hs.PctReturn),
+		formatPercent(hs.Alpha),
+		formatAmount(hs.GrossPnL),
+		formatAmount(hs.Commission),
+		formatAmount(hs.Slippage),
+		formatAmount(hs.BorrowFees),
+		formatAmount(hs.NetPnL),
 	}
 }
+
+// addCosts paid transacting and borrowing to a summary. Gross PnL excludes costs,
+// while net PnL is what was made after they were paid.
+func (hs *holdingSummary) addCosts(commission, slippage, borrow instruments.Amount) {
+	hs.Commission += commission
+	hs.Slippage += slippage
+	hs.BorrowFees += borrow
+	hs.GrossPnL += slippage
+	hs.NetPnL -= commission + borrow
+}
+
+// formatPercent returns a representation of an amount given in hundredths of a percent.
+func formatPercent(amt instruments.Amount) string {
+	return strconv.FormatFloat(float64(amt)/100, 'f', 2, 64) + "%"
+}
+
+// formatPrice returns a dollar representation of a price.
+func formatPrice(p instruments.Price) string {
+	return formatAmount(instruments.Amount(p))
+}
+
+// formatAmount returns a dollar representation of an amount.
+func formatAmount(amt instruments.Amount) string {
+	return strconv.FormatFloat(float64(amt)/100, 'f', 2, 64)
+}
 func GetHeaders() []string {
 	return []string{
 		"Name",
@@ -161,6 +379,11 @@ func GetHeaders() []string {
 		"Number of Orders Filled",
 		"Percent Return",
 		"Alpha",
+		"Gross PnL",
+		"Commission",
+		"Slippage",
+		"Borrow Fees",
+		"Net PnL",
 	}
 }
 
@@ -197,14 +420,19 @@ func (hs *holdingSummary) update(h *instruments.Holding) {
 
 func NewHoldingSummary(holdings ...*instruments.Holding) *holdingSummary {
 	var hs = new(holdingSummary)
-	var pctReturns = make([]instruments.Amount, len(holdings))
+	var pctReturns = make([]instruments.Amount, 0, len(holdings))
 
+	// divideAmts returns top / bottom in hundredths of a percent.
 	divideAmts := func(top, bottom instruments.Price) instruments.Amount {
-		return instruments.Amount((top*200 + bottom) / (bottom * 2))
+		return instruments.Amount((top*20000 + bottom) / (bottom * 2))
 	}
 
 	for i := range holdings {
 		hs.update(holdings[i])
+		// Holdings are bought and sold at prices that include slippage.
+		pnl := instruments.NewAmount(holdings[i].Sell.Price-holdings[i].Buy.Price, holdings[i].Volume)
+		hs.GrossPnL += pnl
+		hs.NetPnL += pnl
 		pctReturns = append(
 			pctReturns, divideAmts(holdings[i].Sell.Price-holdings[i].Buy.Price, holdings[i].Buy.Price))
 	}
@@ -212,7 +440,9 @@ func NewHoldingSummary(holdings ...*instruments.Holding) *holdingSummary {
 	for _, pctReturn := range pctReturns {
 		holdingReturn += pctReturn
 	}
-	hs.PctReturn = holdingReturn / instruments.Amount(len(pctReturns)+1)
+	if len(pctReturns) > 0 {
+		hs.PctReturn = holdingReturn / instruments.Amount(len(pctReturns))
+	}
 
 	return hs
 }
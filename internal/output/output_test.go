@@ -0,0 +1,124 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ToCSV(&buf, []string{"a", "b"}, [][]string{{"1", "2"}, {"3", "4,5"}}); err != nil {
+		t.Fatalf("ToCSV() error = %v", err)
+	}
+	if got, want := buf.String(), "a,b\n1,2\n3,\"4,5\"\n"; got != want {
+		t.Errorf("ToCSV() = %q, want %q", got, want)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ToJSON(&buf, [][]string{{"1", "2"}}); err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	if got, want := buf.String(), `[["1","2"]]`; got != want {
+		t.Errorf("ToJSON() = %q, want %q", got, want)
+	}
+}
+
+func TestPerformanceLog_OutputResults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "results.csv")
+
+	if err := NewPerformanceLog().OutputResults(CSV, FileSink(path)); err != nil {
+		t.Fatalf("PerformanceLog.OutputResults() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		path      string
+		wantFirst string
+	}{
+		{"holdings", path, "Name,"},
+		{"equity", filepath.Join(dir, "results_equity.csv"), "Timestamp,"},
+		{"lots", filepath.Join(dir, "results_lots.csv"), "Name,Volume,Buy Price,"},
+		{"audit", filepath.Join(dir, "results_audit.csv"), "Timestamp,Name,Buy,Action,"},
+		{"stats", filepath.Join(dir, "results_stats.csv"), "Statistic,Value\nTotal Return,"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ioutil.ReadFile(tt.path)
+			if err != nil {
+				t.Fatalf("PerformanceLog.OutputResults() error = %v", err)
+			}
+			if !bytes.HasPrefix(got, []byte(tt.wantFirst)) {
+				t.Errorf("PerformanceLog.OutputResults() %v = %q, want prefix %q", tt.name, got, tt.wantFirst)
+			}
+		})
+	}
+
+	if err := NewPerformanceLog().OutputResults(CSV, FileSink(filepath.Join(dir, "missing", "results.csv"))); err == nil {
+		t.Errorf("PerformanceLog.OutputResults() error = nil, want error")
+	}
+}
+
+func TestPerformanceLog_OutputResults_writer(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+	}{
+		{"csv", CSV},
+		{"json", JSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewPerformanceLog().OutputResults(tt.format, &WriterSink{Writer: &buf}); err != nil {
+				t.Fatalf("PerformanceLog.OutputResults() error = %v", err)
+			}
+			switch tt.format {
+			case JSON:
+				var doc map[string]json.RawMessage
+				if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+					t.Fatalf("PerformanceLog.OutputResults() = %q, not one JSON document: %v", buf.String(), err)
+				}
+				for _, name := range []string{"holdings", "equity", "lots", "audit", "stats"} {
+					if _, ok := doc[name]; !ok {
+						t.Errorf("PerformanceLog.OutputResults() is missing %q", name)
+					}
+				}
+			default:
+				if got := len(strings.Split(buf.String(), "\n\n")); got != 5 {
+					t.Errorf("PerformanceLog.OutputResults() = %d tables, want 5", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package output
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Sink opens the io.Writer that a named set of results is written to.
+// Results are named "holdings", "equity", "lots", "audit" and "stats",
+// and Close is called once every set of results has been written.
+type Sink interface {
+	Open(name string, format Format) (io.WriteCloser, error)
+	Close() error
+}
+
+// FileSink writes holding results to the file at its path,
+// and each other set of results to a file named after it.
+// For example, a FileSink of "results.csv" writes equity to "results_equity.csv".
+type FileSink string
+
+func (path FileSink) Open(name string, format Format) (io.WriteCloser, error) {
+	if name == "holdings" {
+		return os.Create(string(path))
+	}
+	return os.Create(siblingPath(string(path), name))
+}
+
+// Close does nothing, as each file is closed once its results are written.
+func (path FileSink) Close() error { return nil }
+
+// siblingPath returns the path that results named by suffix are output to,
+// alongside per-holding results output to pathName.
+func siblingPath(pathName, suffix string) string {
+	ext := filepath.Ext(pathName)
+	return strings.TrimSuffix(pathName, ext) + "_" + suffix + ext
+}
+
+// WriterSink writes every set of results to an io.Writer as a single document:
+// a JSON object keyed by result name, or CSV tables separated by a blank line.
+// The writer is never closed.
+type WriterSink struct {
+	io.Writer
+	format Format
+	opened int
+}
+
+// Open writes the separator between one set of results and the last,
+// and for JSON the key the results are named by.
+func (ws *WriterSink) Open(name string, format Format) (io.WriteCloser, error) {
+	var sep string
+	switch {
+	case format == JSON && ws.opened == 0:
+		sep = "{" + strconv.Quote(name) + ":"
+	case format == JSON:
+		sep = "," + strconv.Quote(name) + ":"
+	case ws.opened > 0:
+		sep = "\n"
+	}
+	ws.format = format
+	ws.opened++
+	if _, err := io.WriteString(ws.Writer, sep); err != nil {
+		return nil, err
+	}
+	return nopCloser{ws.Writer}, nil
+}
+
+// Close ends the document, closing the JSON object results were written to.
+func (ws *WriterSink) Close() error {
+	var opened = ws.opened
+	ws.opened = 0
+	if ws.format != JSON || opened == 0 {
+		return nil
+	}
+	_, err := io.WriteString(ws.Writer, "}\n")
+	return err
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,266 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package stats computes risk-adjusted performance statistics
+// from an equity series and a log of trade profits and losses.
+package stats
+
+import (
+	"math"
+	"time"
+)
+
+const year = 365.25 * 24 * time.Hour
+
+// Point is a value sampled at a point in time.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Stats is a summary of a simulation's performance.
+// Returns, volatility and drawdowns are given as fractions of 1.
+type Stats struct {
+	TotalReturn         float64       `json:"totalReturn"`
+	AnnualizedReturn    float64       `json:"annualizedReturn"`
+	Volatility          float64       `json:"volatility"`
+	Sharpe              float64       `json:"sharpe"`
+	Sortino             float64       `json:"sortino"`
+	Calmar              float64       `json:"calmar"`
+	MaxDrawdown         float64       `json:"maxDrawdown"`
+	MaxDrawdownDuration time.Duration `json:"maxDrawdownDuration"`
+	Trades              int           `json:"trades"`
+	WinRate             float64       `json:"winRate"`
+	ProfitFactor        float64       `json:"profitFactor"`
+	AvgWin              float64       `json:"avgWin"`
+	AvgLoss             float64       `json:"avgLoss"`
+	Expectancy          float64       `json:"expectancy"`
+
+	// Statistics measured against a benchmark.
+	Beta             float64 `json:"beta"`
+	Alpha            float64 `json:"alpha"`
+	TrackingError    float64 `json:"trackingError"`
+	InformationRatio float64 `json:"informationRatio"`
+	UpCapture        float64 `json:"upCapture"`
+	DownCapture      float64 `json:"downCapture"`
+}
+
+// Compute statistics from equity samples in time order, the profit or loss
+// of each closed trade, and an annual risk free rate.
+// Returns are annualized over the time spanned by the equity samples.
+// If benchmark is not empty, it must be sampled at the same times as equity.
+func Compute(equity, benchmark []Point, trades []float64, riskFree float64) Stats {
+	var s Stats
+
+	s.addTrades(trades)
+	if len(equity) < 2 || equity[0].Value == 0 {
+		return s
+	}
+	first, last := equity[0], equity[len(equity)-1]
+	s.TotalReturn = last.Value/first.Value - 1
+	s.MaxDrawdown, s.MaxDrawdownDuration = Drawdown(equity)
+
+	years := float64(last.Time.Sub(first.Time)) / float64(year)
+	if years <= 0 {
+		return s
+	}
+	s.AnnualizedReturn = math.Pow(1+s.TotalReturn, 1/years) - 1
+	if s.MaxDrawdown != 0 {
+		s.Calmar = s.AnnualizedReturn / s.MaxDrawdown
+	}
+
+	returns := Returns(equity)
+	periods := float64(len(returns)) / years
+	target := riskFree / periods
+
+	mean, stdev := meanStdev(returns)
+	s.Volatility = stdev * math.Sqrt(periods)
+	if stdev != 0 {
+		s.Sharpe = (mean - target) / stdev * math.Sqrt(periods)
+	}
+	if downside := downsideDev(returns, target); downside != 0 {
+		s.Sortino = (mean - target) / downside * math.Sqrt(periods)
+	}
+	if len(benchmark) == len(equity) {
+		s.compare(returns, Returns(benchmark), target, periods)
+	}
+	return s
+}
+
+// compare portfolio returns to benchmark returns over the same periods.
+// Alpha is annualized as a simple return, and target is the risk free
+// rate per period.
+func (s *Stats) compare(returns, benchmark []float64, target, periods float64) {
+	if len(returns) != len(benchmark) || len(returns) == 0 {
+		return
+	}
+	var excess = make([]float64, len(returns))
+	var up, upBench, down, downBench float64
+
+	for i := range returns {
+		excess[i] = returns[i] - benchmark[i]
+		switch {
+		case benchmark[i] > 0:
+			up += returns[i]
+			upBench += benchmark[i]
+		case benchmark[i] < 0:
+			down += returns[i]
+			downBench += benchmark[i]
+		}
+	}
+	mean, _ := meanStdev(returns)
+	benchMean, benchStdev := meanStdev(benchmark)
+	if benchStdev != 0 {
+		s.Beta = covariance(returns, benchmark) / (benchStdev * benchStdev)
+	}
+	s.Alpha = ((mean - target) - s.Beta*(benchMean-target)) * periods
+
+	excessMean, excessStdev := meanStdev(excess)
+	s.TrackingError = excessStdev * math.Sqrt(periods)
+	if excessStdev != 0 {
+		s.InformationRatio = excessMean / excessStdev * math.Sqrt(periods)
+	}
+	if upBench != 0 {
+		s.UpCapture = up / upBench
+	}
+	if downBench != 0 {
+		s.DownCapture = down / downBench
+	}
+}
+
+// addTrades computes trade statistics from the profit or loss of each trade.
+func (s *Stats) addTrades(trades []float64) {
+	var wins, losses int
+	var won, lost float64
+
+	for _, pnl := range trades {
+		switch {
+		case pnl > 0:
+			wins++
+			won += pnl
+		case pnl < 0:
+			losses++
+			lost -= pnl
+		}
+	}
+	s.Trades = len(trades)
+	if s.Trades == 0 {
+		return
+	}
+	s.WinRate = float64(wins) / float64(s.Trades)
+	if wins > 0 {
+		s.AvgWin = won / float64(wins)
+	}
+	if losses > 0 {
+		s.AvgLoss = lost / float64(losses)
+	}
+	if lost != 0 {
+		s.ProfitFactor = won / lost
+	}
+	s.Expectancy = s.WinRate*s.AvgWin - (1-s.WinRate)*s.AvgLoss
+}
+
+// Returns computes the return between each pair of consecutive points.
+func Returns(series []Point) []float64 {
+	var returns = make([]float64, 0, len(series))
+	for i := 1; i < len(series); i++ {
+		if series[i-1].Value == 0 {
+			continue
+		}
+		returns = append(returns, series[i].Value/series[i-1].Value-1)
+	}
+	return returns
+}
+
+// Drawdown returns the largest peak to trough fall in a series,
+// and the longest time the series took to recover to a previous peak.
+// A series that has not recovered by its last point is counted as
+// underwater until then.
+func Drawdown(series []Point) (max float64, duration time.Duration) {
+	if len(series) == 0 {
+		return 0, 0
+	}
+	var peak = series[0]
+	var underwater bool
+
+	for _, p := range series[1:] {
+		if p.Value >= peak.Value {
+			if d := p.Time.Sub(peak.Time); underwater && d > duration {
+				duration = d
+			}
+			peak, underwater = p, false
+			continue
+		}
+		underwater = true
+		if dd := (peak.Value - p.Value) / peak.Value; dd > max {
+			max = dd
+		}
+		if d := p.Time.Sub(peak.Time); d > duration {
+			duration = d
+		}
+	}
+	return max, duration
+}
+
+func meanStdev(xs []float64) (mean, stdev float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	if len(xs) < 2 {
+		return mean, 0
+	}
+	for _, x := range xs {
+		stdev += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(stdev / float64(len(xs)-1))
+}
+
+// covariance is the sample covariance of two series of the same length.
+func covariance(xs, ys []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	xMean, _ := meanStdev(xs)
+	yMean, _ := meanStdev(ys)
+
+	var sum float64
+	for i := range xs {
+		sum += (xs[i] - xMean) * (ys[i] - yMean)
+	}
+	return sum / float64(len(xs)-1)
+}
+
+// downsideDev is the deviation of returns that fall below target.
+func downsideDev(xs []float64, target float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, x := range xs {
+		if x < target {
+			sum += (x - target) * (x - target)
+		}
+	}
+	return math.Sqrt(sum / float64(len(xs)))
+}
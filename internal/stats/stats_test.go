@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package stats
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2017, 8, 14, 0, 0, 0, 0, time.UTC)
+
+func mockSeries(values ...float64) []Point {
+	var series = make([]Point, len(values))
+	for i, v := range values {
+		series[i] = Point{Time: start.AddDate(0, 0, i), Value: v}
+	}
+	return series
+}
+
+func TestDrawdown(t *testing.T) {
+	tests := []struct {
+		name         string
+		series       []Point
+		wantMax      float64
+		wantDuration time.Duration
+	}{
+		{"no drawdown", mockSeries(100, 110, 120), 0, 0},
+		{"recovered", mockSeries(100, 80, 90, 100, 120), 0.2, 3 * 24 * time.Hour},
+		{"not recovered", mockSeries(100, 120, 90, 100), 0.25, 2 * 24 * time.Hour},
+		{"empty", mockSeries(), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMax, gotDuration := Drawdown(tt.series)
+			if math.Abs(gotMax-tt.wantMax) > 1e-9 {
+				t.Errorf("Drawdown() max = %v, want %v", gotMax, tt.wantMax)
+			}
+			if gotDuration != tt.wantDuration {
+				t.Errorf("Drawdown() duration = %v, want %v", gotDuration, tt.wantDuration)
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	var yearly = []Point{
+		{Time: start, Value: 100},
+		{Time: start.Add(year / 2), Value: 90},
+		{Time: start.Add(year), Value: 121},
+	}
+	got := Compute(yearly, nil, []float64{30, -10, 20, -20}, 0)
+
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"total return", got.TotalReturn, 0.21},
+		{"annualized return", got.AnnualizedReturn, 0.21},
+		{"max drawdown", got.MaxDrawdown, 0.1},
+		{"calmar", got.Calmar, 2.1},
+		{"win rate", got.WinRate, 0.5},
+		{"avg win", got.AvgWin, 25},
+		{"avg loss", got.AvgLoss, 15},
+		{"profit factor", got.ProfitFactor, 50.0 / 30.0},
+		{"expectancy", got.Expectancy, 5},
+		{"volatility", got.Volatility, 4.0 / 9.0},
+		{"sharpe", got.Sharpe, 0.55},
+		{"sortino", got.Sortino, 22.0 / 9.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > 1e-9 {
+				t.Errorf("Compute() %v = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+	if got.Trades != 4 {
+		t.Errorf("Compute() trades = %v, want %v", got.Trades, 4)
+	}
+}
+
+func TestCompute_benchmark(t *testing.T) {
+	var series = func(values ...float64) []Point {
+		var points = make([]Point, len(values))
+		for i, v := range values {
+			points[i] = Point{Time: start.Add(year * time.Duration(i) / 4), Value: v}
+		}
+		return points
+	}
+	// Portfolio returns are twice the benchmark's plus 1% each quarter.
+	var benchmark = series(100, 110, 99, 108.9, 119.79)
+	var equity = series(100, 121, 98.01, 118.5921, 143.496441)
+
+	got := Compute(equity, benchmark, nil, 0)
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"beta", got.Beta, 2},
+		{"alpha", got.Alpha, 0.04},
+		{"up capture", got.UpCapture, 2.1},
+		{"down capture", got.DownCapture, 1.9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > 1e-3 {
+				t.Errorf("Compute() %v = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+	if got.TrackingError <= 0 || got.InformationRatio <= 0 {
+		t.Errorf("Compute() tracking error = %v, information ratio = %v", got.TrackingError, got.InformationRatio)
+	}
+	if same := Compute(benchmark, benchmark, nil, 0); math.Abs(same.Beta-1) > 1e-9 || same.TrackingError != 0 {
+		t.Errorf("Compute() against itself beta = %v, tracking error = %v", same.Beta, same.TrackingError)
+	}
+}
@@ -22,13 +22,18 @@ package worker
 
 import (
 	"bufio"
+	"encoding/csv"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"math"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jakeschurch/instruments"
 )
@@ -37,10 +42,87 @@ type Config struct {
 	Name, Bid, BidSz, Ask, AskSz, Timestamp uint8
 	Timeunit                                string
 	Date                                    time.Time
+	// Parsers is the number of goroutines that parse records in parallel.
+	// If zero, one is started for each CPU.
+	Parsers int
+
+	// Delim separates the fields of a record, and defaults to "|".
+	// If Quoted is set, fields may be quoted as in RFC 4180.
+	Delim   string
+	Quoted  bool
+	Headers bool
+	// Lines starting with any of Comments are skipped.
+	Comments []string
+
+	// Headings name columns by their header text. A named column overrides
+	// its position, and the first record of the file is read as headers.
+	Headings struct {
+		Name, Bid, BidSz, Ask, AskSz, Timestamp string
+	}
 }
 type Worker struct {
-	dataChan chan []string
-	config   Config
+	config Config
+	err    error
+}
+
+// MissingColumnsError is returned when columns named by their headings
+// are not found in a file's header row.
+type MissingColumnsError struct {
+	Missing, Headers []string
+}
+
+func (e *MissingColumnsError) Error() string {
+	return fmt.Sprintf("columns %q not found; available headers are %q", e.Missing, e.Headers)
+}
+
+// resolve the positions of named columns from a header row.
+func (c *Config) resolve(headers []string) error {
+	var columns = []struct {
+		pos  *uint8
+		name string
+	}{
+		{&c.Name, c.Headings.Name}, {&c.Timestamp, c.Headings.Timestamp},
+		{&c.Bid, c.Headings.Bid}, {&c.BidSz, c.Headings.BidSz},
+		{&c.Ask, c.Headings.Ask}, {&c.AskSz, c.Headings.AskSz},
+	}
+	var missing = make([]string, 0)
+
+	for _, col := range columns {
+		if col.name == "" {
+			continue
+		}
+		var found bool
+		for i, header := range headers {
+			if strings.TrimSpace(header) == col.name && i <= math.MaxUint8 {
+				*col.pos, found = uint8(i), true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, col.name)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingColumnsError{Missing: missing, Headers: headers}
+	}
+	return nil
+}
+
+// named returns true if any column is named by its heading.
+func (c Config) named() bool {
+	var h = c.Headings
+	return h.Name != "" || h.Timestamp != "" || h.Bid != "" || h.BidSz != "" || h.Ask != "" || h.AskSz != ""
+}
+
+// width returns the number of fields a record needs to hold every column.
+func (c Config) width() int {
+	var max = c.Name
+	for _, pos := range []uint8{c.Bid, c.BidSz, c.Ask, c.AskSz, c.Timestamp} {
+		if pos > max {
+			max = pos
+		}
+	}
+	return int(max) + 1
 }
 
 func New(wc Config) *Worker {
@@ -49,70 +131,197 @@ func New(wc Config) *Worker {
 	}
 }
 
-func (worker *Worker) Run(outChan chan<- *instruments.Quote, r io.ReadSeeker) {
-	var lineCount int
-	var wg sync.WaitGroup
-	wg.Add(2)
+// batchSize is the number of lines read from a file before they are parsed.
+const batchSize = 256
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		lineCount++
-	}
-	r.Seek(0, 0)
+// batch is a run of lines from a file, numbered in the order they were read
+// so that quotes can be sent on in that same order once parsed.
+type batch struct {
+	seq     int
+	records [][]string
+	quotes  []*instruments.Quote
+}
 
-	worker.dataChan = make(chan []string, lineCount)
+// Run reads quotes from r, and sends them on outChan in the order they
+// were read; outChan is closed once r has been read.
+// Lines are read once, in batches: records are parsed in parallel, and
+// no more than two batches per parser are read ahead of the batch being
+// sent, so that reading blocks while quotes are waiting to be received.
+func (worker *Worker) Run(outChan chan<- *instruments.Quote, r io.Reader) {
+	var parsers = worker.config.Parsers
+	if parsers <= 0 {
+		parsers = runtime.NumCPU()
+	}
+	var produced = make(chan *batch)
+	var read = make(chan *batch, parsers)
+	var parsed = make(chan *batch, parsers)
 
+	// window holds a slot for each batch read but not yet sent,
+	// which bounds the batches held until earlier ones are sent.
+	var window = make(chan struct{}, 2*parsers)
+	go worker.produce(r, produced)
 	go func() {
-		for {
-			data, ok := <-worker.dataChan
-			if !ok {
-				if len(worker.dataChan) == 0 {
-					close(outChan)
-					break
-				}
+		defer close(read)
+		for b := range produced {
+			window <- struct{}{}
+			read <- b
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(parsers)
+	for i := 0; i < parsers; i++ {
+		go func() {
+			defer wg.Done()
+			for b := range read {
+				worker.parse(b)
+				parsed <- b
 			}
-			quote, err := worker.consume(data)
-			if quote != nil && err == nil {
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(parsed)
+	}()
+
+	// Parsers may finish batches out of order;
+	// hold them until every earlier batch has been sent.
+	var next int
+	var pending = make(map[int]*batch)
+	for b := range parsed {
+		pending[b.seq] = b
+		for b, ok := pending[next]; ok; b, ok = pending[next] {
+			for _, quote := range b.quotes {
 				outChan <- quote
 			}
+			delete(pending, next)
+			next++
+			<-window
 		}
-		defer wg.Done()
-	}()
-	go worker.produce(r, &wg)
-
-	wg.Wait()
+	}
+	close(outChan)
 }
 
-func (worker *Worker) produce(r io.ReadSeeker, wg *sync.WaitGroup) {
-	defer wg.Done()
-	scanner := bufio.NewScanner(r)
-	scanner.Split(bufio.ScanLines)
+// Err returns the error that stopped a worker from reading a file, if any,
+// such as a read error or a MissingColumnsError.
+// It should be called once the worker's quote channel has been closed.
+func (worker *Worker) Err() error {
+	return worker.err
+}
 
-	scanner.Scan() // for headers...
-	for scanner.Scan() {
-		line := scanner.Text()
+// produce reads records from r in batches, skipping the header line
+// if the file has headers, or resolving named columns from it.
+// Records too short to hold every column are skipped.
+func (worker *Worker) produce(r io.Reader, out chan<- *batch) {
+	defer close(out)
+	var next = worker.reader(r)
+	var headers = worker.config.Headers || worker.config.named()
+	var width = worker.config.width()
 
-		// Check to see if error has been thrown or
-		if err := scanner.Err(); err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				log.Fatalln(err)
+	var b = &batch{records: make([][]string, 0, batchSize)}
+	for {
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+		if _, ok := err.(*csv.ParseError); ok {
+			continue
+		}
+		if err != nil {
+			worker.err = err
+			return
+		}
+		if headers {
+			headers = false
+			if !worker.config.named() {
+				continue
 			}
+			if worker.err = worker.config.resolve(record); worker.err != nil {
+				return
+			}
+			width = worker.config.width()
+			continue
+		}
+		if len(record) < width {
+			continue
 		}
-		record := strings.Split(line, "|")
-		if len(record) >= 4 {
-			worker.dataChan <- record
+		b.records = append(b.records, record)
+		if len(b.records) == batchSize {
+			out <- b
+			b = &batch{seq: b.seq + 1, records: make([][]string, 0, batchSize)}
 		}
 	}
-	close(worker.dataChan)
+	if len(b.records) > 0 {
+		out <- b
+	}
 	log.Println("done reading from file")
 }
 
+// reader returns a function that reads the next record from r, skipping comments.
+// It returns io.EOF once r has been read.
+func (worker *Worker) reader(r io.Reader) func() ([]string, error) {
+	var delim = worker.config.Delim
+	if delim == "" {
+		delim = "|"
+	}
+
+	if worker.config.Quoted {
+		var cr = csv.NewReader(r)
+		cr.Comma, _ = utf8.DecodeRuneInString(delim)
+		cr.FieldsPerRecord = -1
+		return func() ([]string, error) {
+			for {
+				record, err := cr.Read()
+				if err != nil || !worker.comment(record[0]) {
+					return record, err
+				}
+			}
+		}
+	}
+
+	var scanner = bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	return func() ([]string, error) {
+		for scanner.Scan() {
+			if line := scanner.Text(); !worker.comment(line) {
+				return strings.Split(line, delim), nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
+
+// comment returns true if a line starts with a comment prefix.
+func (worker *Worker) comment(line string) bool {
+	for _, prefix := range worker.config.Comments {
+		if prefix != "" && strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parse the records of a batch into quotes, dropping records that cannot be parsed.
+func (worker *Worker) parse(b *batch) {
+	b.quotes = make([]*instruments.Quote, 0, len(b.records))
+	for _, record := range b.records {
+		if quote, err := worker.consume(record); quote != nil && err == nil {
+			b.quotes = append(b.quotes, quote)
+		}
+	}
+	b.records = nil
+}
+
 var ErrParseRecord = errors.New("record could not be parsed correctly")
 
 func (worker *Worker) consume(record []string) (*instruments.Quote, error) {
-	var quote = &instruments.Quote{}
+	var quote = &instruments.Quote{
+		Bid: &instruments.QuotedMetric{},
+		Ask: &instruments.QuotedMetric{},
+	}
 
 	quote.Name = record[worker.config.Name]
 
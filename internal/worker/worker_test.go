@@ -21,11 +21,14 @@
 package worker
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"reflect"
-	"sync"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jakeschurch/goat/internal/config"
 
@@ -38,11 +41,12 @@ func mockWc() Config {
 
 	// Setup Worker & WorkerConfig
 	return Config{
-		Name: conf.File.Columns.Ticker,
-		Bid:  conf.File.Columns.Bid, BidSz: conf.File.Columns.BidSize,
-		Ask: conf.File.Columns.Ask, AskSz: conf.File.Columns.AskSize,
-		Timestamp: conf.File.Columns.Timestamp, Date: date,
+		Name: conf.File.Columns.Ticker.Index,
+		Bid:  conf.File.Columns.Bid.Index, BidSz: conf.File.Columns.BidSize.Index,
+		Ask: conf.File.Columns.Ask.Index, AskSz: conf.File.Columns.AskSize.Index,
+		Timestamp: conf.File.Columns.Timestamp.Index, Date: date,
 		Timeunit: conf.File.TimestampUnit,
+		Delim:    conf.File.Delim, Headers: conf.File.Headers,
 	}
 }
 func TestNew(t *testing.T) {
@@ -81,7 +85,7 @@ func TestWorker_Run(t *testing.T) {
 	file, _ := os.Open("../../example/config.json")
 	type args struct {
 		outChan chan<- *instruments.Quote
-		r       io.ReadSeeker
+		r       io.Reader
 	}
 	tests := []struct {
 		name   string
@@ -98,21 +102,167 @@ func TestWorker_Run(t *testing.T) {
 	}
 }
 
+// mockFile returns a header line followed by n quote lines,
+// cycling through symbols, with the line number as the timestamp.
+func mockFile(n int) string {
+	var b strings.Builder
+	b.WriteString("Time|Symbol|Bid|BidSize|Ask|AskSize\n")
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "%d|%s|10.00|1|10.02|1\n", i, []string{"AAPL", "MSFT", "GOOG"}[i%3])
+	}
+	return b.String()
+}
+
 func TestWorker_produce(t *testing.T) {
-	type args struct {
-		r  io.ReadSeeker
-		wg *sync.WaitGroup
+	tests := []struct {
+		name        string
+		file        string
+		wantBatches int
+		wantRecords int
+	}{
+		{"header only", mockFile(0), 0, 0},
+		{"one batch", mockFile(10), 1, 10},
+		{"full batches", mockFile(2 * batchSize), 2, 2 * batchSize},
+		{"partial batch", mockFile(batchSize + 1), 2, batchSize + 1},
+		{"short lines", mockFile(3) + "x|y\n4|AAPL|10.00|1|10.02\n", 1, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out = make(chan *batch)
+			go New(Config{Name: 1, Timestamp: 0, Bid: 2, BidSz: 3, Ask: 4, AskSz: 5, Headers: true}).produce(strings.NewReader(tt.file), out)
+
+			var batches, records int
+			for b := range out {
+				if b.seq != batches {
+					t.Errorf("Worker.produce() batch seq = %d, want %d", b.seq, batches)
+				}
+				batches++
+				records += len(b.records)
+			}
+			if batches != tt.wantBatches || records != tt.wantRecords {
+				t.Errorf("Worker.produce() = %d batches of %d records, want %d of %d", batches, records, tt.wantBatches, tt.wantRecords)
+			}
+		})
 	}
+}
+
+func TestWorker_produce_formats(t *testing.T) {
 	tests := []struct {
 		name   string
-		worker *Worker
-		args   args
+		config Config
+		file   string
+		want   [][]string
 	}{
-		// TODO: Add test cases.
+		{"pipe", Config{}, "1|AAPL|10|1\n2|MSFT|20|1\n",
+			[][]string{{"1", "AAPL", "10", "1"}, {"2", "MSFT", "20", "1"}}},
+		{"headers", Config{Delim: ",", Headers: true}, "Time,Symbol,Bid,BidSize\n1,AAPL,10,1\n",
+			[][]string{{"1", "AAPL", "10", "1"}}},
+		{"tab", Config{Delim: "\t"}, "1\tAAPL\t10\t1\n",
+			[][]string{{"1", "AAPL", "10", "1"}}},
+		{"comments", Config{Delim: ",", Headers: true, Comments: []string{"#", "//"}},
+			"# exported 2017-08-14\nTime,Symbol,Bid,BidSize\n// AAPL\n1,AAPL,10,1\n",
+			[][]string{{"1", "AAPL", "10", "1"}}},
+		{"quoted", Config{Delim: ",", Quoted: true}, "1,\"AAPL, Inc.\",10,1\n2,\"MS\"\"FT\nCorp\",20,1\n",
+			[][]string{{"1", "AAPL, Inc.", "10", "1"}, {"2", "MS\"FT\nCorp", "20", "1"}}},
+		{"quoted parse error", Config{Delim: ",", Quoted: true, Comments: []string{"#"}}, "#,x\n1,A\"APL,10,1\n2,MSFT,20,1\n",
+			[][]string{{"2", "MSFT", "20", "1"}}},
+		{"unquoted", Config{Delim: ","}, "1,\"AAPL, Inc.\",10,1\n",
+			[][]string{{"1", "\"AAPL", " Inc.\"", "10", "1"}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.worker.produce(tt.args.r, tt.args.wg)
+			var out = make(chan *batch)
+			go New(tt.config).produce(strings.NewReader(tt.file), out)
+
+			var got = make([][]string, 0)
+			for b := range out {
+				got = append(got, b.records...)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Worker.produce() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorker_Run_headings(t *testing.T) {
+	var named = Config{Timeunit: "ns", Parsers: 2}
+	named.Headings.Name, named.Headings.Timestamp = "Symbol", "Time"
+	named.Headings.Bid, named.Headings.BidSz = "Bid", "BidSize"
+	named.Headings.Ask, named.Headings.AskSz = "Ask", "AskSize"
+	var missing = named
+	missing.Headings.Bid, missing.Headings.Ask = "Best_Bid_Price", "Best_Offer_Price"
+
+	tests := []struct {
+		name    string
+		config  Config
+		file    string
+		want    int
+		wantErr error
+	}{
+		{"named", named, mockFile(10), 10, nil},
+		{"reordered", named, "Bid|BidSize|Ask|AskSize|Time|Symbol\n10.00|1|10.02|1|1|AAPL\n", 1, nil},
+		{"ragged", named, "Bid|BidSize|Ask|AskSize|Time|Symbol\n10.00|1|10.02|1|1\n10.00|1|10.02|1|1|AAPL\n", 1, nil},
+		{"missing", missing, mockFile(10), 0, &MissingColumnsError{
+			Missing: []string{"Best_Bid_Price", "Best_Offer_Price"},
+			Headers: []string{"Time", "Symbol", "Bid", "BidSize", "Ask", "AskSize"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w = New(tt.config)
+			var quoteChan = make(chan *instruments.Quote)
+			go w.Run(quoteChan, strings.NewReader(tt.file))
+
+			var got int
+			for quote := range quoteChan {
+				if quote.Bid.Price != 1000 || quote.Ask.Price != 1002 {
+					t.Errorf("Worker.Run() quote = %v, %v, want $10.00, $10.02", quote.Bid, quote.Ask)
+				}
+				got++
+			}
+			if got != tt.want {
+				t.Errorf("Worker.Run() sent %d quotes, want %d", got, tt.want)
+			}
+			if !reflect.DeepEqual(w.Err(), tt.wantErr) {
+				t.Errorf("Worker.Err() = %v, want %v", w.Err(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorker_Run_ragged(t *testing.T) {
+	var w = New(Config{Name: 0, Timestamp: 1, Bid: 16, BidSz: 17, Ask: 18, AskSz: 19, Timeunit: "ns", Delim: ","})
+	var quoteChan = make(chan *instruments.Quote)
+	go w.Run(quoteChan, strings.NewReader("AAPL,1,10.00,1,10.02\n"))
+
+	var got int
+	for range quoteChan {
+		got++
+	}
+	if got != 0 || w.Err() != nil {
+		t.Errorf("Worker.Run() sent %d quotes, err %v, want 0, <nil>", got, w.Err())
+	}
+}
+
+func TestWorker_Run_order(t *testing.T) {
+	const lines = 10*batchSize + 7
+	for _, parsers := range []int{1, 4, 16} {
+		t.Run(strconv.Itoa(parsers), func(t *testing.T) {
+			var w = New(Config{Name: 1, Timestamp: 0, Bid: 2, BidSz: 3, Ask: 4, AskSz: 5, Timeunit: "ns", Parsers: parsers, Headers: true})
+			var quoteChan = make(chan *instruments.Quote)
+			go w.Run(quoteChan, strings.NewReader(mockFile(lines)))
+
+			var got int
+			for quote := range quoteChan {
+				got++
+				if want := time.Duration(got); quote.Timestamp.Sub(time.Time{}) != want {
+					t.Fatalf("Worker.Run() quote %d timestamp = %v, want %v", got, quote.Timestamp.Sub(time.Time{}), want)
+				}
+			}
+			if got != lines {
+				t.Errorf("Worker.Run() sent %d quotes, want %d", got, lines)
+			}
 		})
 	}
 }
@@ -0,0 +1,139 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/jakeschurch/instruments"
+)
+
+// CostMethod selects the holdings, or lots, that are relieved
+// when part of a position is sold off.
+type CostMethod int
+
+const (
+	// FIFO relieves the earliest bought lots first.
+	FIFO CostMethod = iota // 0
+	// LIFO relieves the most recently bought lots first.
+	LIFO
+	// HIFO relieves the highest cost lots first.
+	HIFO
+	// LOFO relieves the lowest cost lots first.
+	LOFO
+	// AvgCost relieves lots in FIFO order, each at the
+	// average cost of all lots held.
+	AvgCost
+	// SpecificLot relieves the lots an order was created for,
+	// then any volume left over in FIFO order.
+	SpecificLot
+)
+
+var ErrCostMethod = errors.New("unknown cost method")
+
+// costMethods are the names cost methods are set by in a config.
+var costMethods = map[string]CostMethod{
+	"fifo": FIFO, "lifo": LIFO, "hifo": HIFO, "lofo": LOFO,
+	"average": AvgCost, "specific": SpecificLot,
+}
+
+// ParseCostMethod returns the cost method named "fifo", "lifo", "hifo",
+// "lofo", "average" or "specific". An empty name is FIFO.
+func ParseCostMethod(name string) (CostMethod, error) {
+	if name == "" {
+		return FIFO, nil
+	}
+	if method, ok := costMethods[strings.ToLower(name)]; ok {
+		return method, nil
+	}
+	return FIFO, ErrCostMethod
+}
+
+// reliefOrder returns open lots in the order they are relieved by method.
+// If short, open lots are short holdings, otherwise they are long holdings.
+// Named lots come first if method is SpecificLot.
+func reliefOrder(method CostMethod, short bool, lots []*instruments.Holding, named ...*instruments.Holding) []*instruments.Holding {
+	var open = make([]*instruments.Holding, 0, len(lots))
+	for _, lot := range lots {
+		if (short && lot.Volume < 0) || (!short && lot.Volume > 0) {
+			open = append(open, lot)
+		}
+	}
+
+	switch method {
+	case LIFO:
+		for i, j := 0, len(open)-1; i < j; i, j = i+1, j-1 {
+			open[i], open[j] = open[j], open[i]
+		}
+	case HIFO:
+		sort.SliceStable(open, func(i, j int) bool {
+			return open[i].Buy.Price > open[j].Buy.Price
+		})
+	case LOFO:
+		sort.SliceStable(open, func(i, j int) bool {
+			return open[i].Buy.Price < open[j].Buy.Price
+		})
+	case SpecificLot:
+		var first = make([]*instruments.Holding, 0, len(open))
+		var rest = make([]*instruments.Holding, 0, len(open))
+		for _, lot := range open {
+			if isNamed(lot, named) {
+				first = append(first, lot)
+			} else {
+				rest = append(rest, lot)
+			}
+		}
+		open = append(first, rest...)
+	}
+	return open
+}
+
+func isNamed(lot *instruments.Holding, named []*instruments.Holding) bool {
+	for i := range named {
+		if named[i] == lot {
+			return true
+		}
+	}
+	return false
+}
+
+// averageCost rebases every open lot to the
+// volume weighted average cost of all of them.
+func averageCost(lots []*instruments.Holding) {
+	var cost instruments.Amount
+	var volume instruments.Volume
+
+	for _, lot := range lots {
+		cost += instruments.NewAmount(lot.Buy.Price, lot.Volume)
+		volume += lot.Volume
+	}
+	if volume == 0 {
+		return
+	}
+	avg := instruments.Price((cost + instruments.Amount(volume)/2) / instruments.Amount(volume))
+	for _, lot := range lots {
+		if lot.Volume != 0 {
+			lot.Buy.Price = avg
+		}
+	}
+}
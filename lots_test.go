@@ -0,0 +1,106 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+func TestOrderManager_Sell_costMethods(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	type lot struct {
+		volume instruments.Volume
+		buy    float64
+		pnl    float64
+	}
+	tests := []struct {
+		name     string
+		method   CostMethod
+		wantLots []lot
+	}{
+		{"FIFO", FIFO, []lot{{10, 10, 30}, {5, 12, 5}}},
+		{"LIFO", LIFO, []lot{{10, 11, 20}, {5, 12, 5}}},
+		{"HIFO", HIFO, []lot{{10, 12, 10}, {5, 11, 10}}},
+		{"LOFO", LOFO, []lot{{10, 10, 30}, {5, 11, 10}}},
+		{"AvgCost", AvgCost, []lot{{10, 11, 20}, {5, 11, 10}}},
+		{"SpecificLot", SpecificLot, []lot{{10, 11, 20}, {5, 10, 15}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			om.costMethod = tt.method
+			for _, price := range []float64{10, 12, 11} {
+				om.Add(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(price), 10, ts))
+			}
+			holdings, err := om.port.GetSlice("AAPL")
+			if err != nil {
+				t.Fatalf("Portfolio.GetSlice() error = %v", err)
+			}
+			quote := mockQuote("AAPL", 13, 13.05)
+			quote.Timestamp = ts.Add(time.Hour)
+			om.Match(quote)
+			om.Relieve(NewOrder("AAPL", false, instruments.Market, instruments.NewPrice(13), 15, ts), holdings[2])
+
+			got := om.log.Lots()
+			if len(got) != len(tt.wantLots) {
+				t.Fatalf("OrderManager.Sell() lots = %v, want %v", got, tt.wantLots)
+			}
+			for i, want := range tt.wantLots {
+				if got[i].Volume != want.volume || got[i].BuyPrice != instruments.NewPrice(want.buy) ||
+					got[i].RealizedPnL != instruments.NewAmount(instruments.NewPrice(want.pnl), 1) {
+					t.Errorf("OrderManager.Sell() lot %d = %+v, want %+v", i, got[i], want)
+				}
+				if got[i].SellDate.Before(got[i].BuyDate) || !got[i].SellDate.Equal(quote.Timestamp) {
+					t.Errorf("OrderManager.Sell() lot %d bought %v, sold %v, want sold at %v", i, got[i].BuyDate, got[i].SellDate, quote.Timestamp)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCostMethod(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    CostMethod
+		wantErr error
+	}{
+		{"", FIFO, nil},
+		{"fifo", FIFO, nil},
+		{"LIFO", LIFO, nil},
+		{"hifo", HIFO, nil},
+		{"lofo", LOFO, nil},
+		{"average", AvgCost, nil},
+		{"specific", SpecificLot, nil},
+		{"1", FIFO, ErrCostMethod},
+		{"fiffo", FIFO, ErrCostMethod},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCostMethod(tt.name)
+			if got != tt.want || err != tt.wantErr {
+				t.Errorf("ParseCostMethod() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+// MarginError is returned when an order needs more buying power than a portfolio has.
+type MarginError struct {
+	Required    instruments.Amount
+	BuyingPower instruments.Amount
+}
+
+func (e *MarginError) Error() string {
+	return fmt.Sprintf("order requires %.2f of buying power, only %.2f available",
+		float64(e.Required)/100, float64(e.BuyingPower)/100)
+}
+
+// Margin is the terms a portfolio borrows cash on to buy securities,
+// and holds short holdings on.
+type Margin struct {
+	// Initial is the fraction of a new position's value that must be covered by equity.
+	Initial float64
+	// Maintenance is the fraction of gross exposure that must be covered by equity;
+	// below it, holdings are liquidated.
+	Maintenance float64
+	// Rate is the annual interest charged on negative cash,
+	// given as a fraction of 1. Interest accrues daily over a 360 day year.
+	Rate float64
+}
+
+// cashAccount is the terms shorts are opened on without margin,
+// where equity must cover the full value of every position.
+var cashAccount = &Margin{Initial: 1}
+
+// RegT returns Reg-T style terms of 50% initial and 25% maintenance margin.
+func RegT() *Margin {
+	return &Margin{Initial: 0.50, Maintenance: 0.25}
+}
+
+// PortfolioMargin returns terms with a single margin percent,
+// given as a fraction of 1, for both initial and maintenance margin.
+func PortfolioMargin(pct float64) *Margin {
+	return &Margin{Initial: pct, Maintenance: pct}
+}
+
+// NewMargin returns the margin terms set in a config,
+// or nil if buys are limited by cash.
+// The "portfolio" model defaults to a margin of 15%.
+func NewMargin(c config.Config) *Margin {
+	var conf = c.Backtest.Margin
+	var m *Margin
+
+	switch conf.Model {
+	case "regT":
+		m = RegT()
+		if conf.Maintenance != 0 {
+			m.Maintenance = conf.Maintenance
+		}
+	case "portfolio":
+		m = PortfolioMargin(0.15)
+		if conf.Maintenance != 0 {
+			m = PortfolioMargin(conf.Maintenance)
+		}
+	default:
+		return nil
+	}
+	if conf.Initial != 0 {
+		m.Initial = conf.Initial
+	}
+	m.Rate = conf.InterestRate
+	return m
+}
+
+// BuyingPower returns the value of new positions that equity can cover at initial margin.
+func (m *Margin) BuyingPower(e output.Equity) instruments.Amount {
+	var excess = float64(e.Equity) - m.Initial*float64(e.GrossExposure)
+	if excess <= 0 {
+		return 0
+	}
+	return instruments.Amount(excess / m.Initial)
+}
+
+// Check that equity can cover opening new positions worth amt.
+func (m *Margin) Check(e output.Equity, amt instruments.Amount) error {
+	if bp := m.BuyingPower(e); bp < amt {
+		return &MarginError{Required: amt, BuyingPower: bp}
+	}
+	return nil
+}
+
+// called reports whether equity has fallen below maintenance margin.
+func (m *Margin) called(e output.Equity) bool {
+	return float64(e.Equity) < m.Maintenance*float64(e.GrossExposure)
+}
+
+// ----------------------------------------------------------------------------
+
+// MarginCall liquidates holdings, largest first, while the portfolio's
+// equity is below maintenance margin. It reports whether any were liquidated.
+func (o *OrderManager) MarginCall(timestamp time.Time) bool {
+	if o.margin == nil {
+		return false
+	}
+	var liquidated = make(map[string]bool)
+	for o.margin.called(o.port.Equity(timestamp)) {
+		name, order := o.port.largest(liquidated)
+		if order == nil {
+			break
+		}
+		liquidated[name] = true
+		o.ForceFill(order)
+	}
+	return len(liquidated) > 0
+}
+
+// chargeInterest on negative cash for a number of days.
+func (o *OrderManager) chargeInterest(days int) {
+	if o.margin == nil || o.margin.Rate == 0 || o.port.cash >= 0 {
+		return
+	}
+	o.port.cash -= instruments.Amount(math.Round(float64(-o.port.cash) * o.margin.Rate / 360 * float64(days)))
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/instruments"
+)
+
+func TestNewMargin(t *testing.T) {
+	tests := []struct {
+		name        string
+		model       string
+		initial     float64
+		maintenance float64
+		want        *Margin
+	}{
+		{"cash", "", 0, 0, nil},
+		{"regT", "regT", 0, 0, &Margin{Initial: 0.50, Maintenance: 0.25}},
+		{"regT maintenance", "regT", 0, 0.30, &Margin{Initial: 0.50, Maintenance: 0.30}},
+		{"portfolio", "portfolio", 0, 0, &Margin{Initial: 0.15, Maintenance: 0.15}},
+		{"portfolio percent", "portfolio", 0, 0.20, &Margin{Initial: 0.20, Maintenance: 0.20}},
+		{"portfolio initial", "portfolio", 0.25, 0.20, &Margin{Initial: 0.25, Maintenance: 0.20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c config.Config
+			c.Backtest.Margin.Model = tt.model
+			c.Backtest.Margin.Initial = tt.initial
+			c.Backtest.Margin.Maintenance = tt.maintenance
+			if got := NewMargin(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewMargin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderManager_Buy_margin(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		margin  *Margin
+		volume  instruments.Volume
+		wantErr error
+	}{
+		{"cash", nil, 100, nil},
+		{"cash exceeded", nil, 150, ErrLowCash},
+		{"regT", RegT(), 150, nil},
+		{"regT exceeded", RegT(), 250, &MarginError{Required: 250000, BuyingPower: 200000}},
+		{"portfolio", PortfolioMargin(0.20), 450, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			om.margin = tt.margin
+			order := NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10), tt.volume, ts)
+			if _, err := om.Buy(order, order.Price, tt.volume); !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("OrderManager.Buy() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrderManager_MarginCall(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		bid      float64
+		want     bool
+		wantHeld instruments.Volume
+	}{
+		{"above maintenance", 8, false, 200},
+		{"below maintenance", 6, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			om.margin = RegT()
+			om.Add(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10), 200, ts))
+			om.port.Update(mockQuote("AAPL", tt.bid, tt.bid+1), om)
+
+			if got := om.MarginCall(ts); got != tt.want {
+				t.Errorf("OrderManager.MarginCall() = %v, want %v", got, tt.want)
+			}
+			if got := om.held("AAPL"); got != tt.wantHeld {
+				t.Errorf("OrderManager.MarginCall() held = %v, want %v", got, tt.wantHeld)
+			}
+		})
+	}
+}
+
+func TestOrderManager_Accrue_interest(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+
+	om := mockOrderManager(1000)
+	om.margin = &Margin{Initial: 0.5, Maintenance: 0.25, Rate: 0.36}
+	om.port.cash = -100000
+	om.Accrue(ts)
+	om.Accrue(ts.AddDate(0, 0, 1))
+	if want := instruments.Amount(-100100); om.port.cash != want {
+		t.Errorf("OrderManager.Accrue() cash = %v, want %v", om.port.cash, want)
+	}
+}
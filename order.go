@@ -0,0 +1,202 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"time"
+
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+// Order is an order placed by an algorithm. It extends an instruments.Order
+// with the stop, trailing stop, time in force and order group logic
+// that the OrderManager supports.
+type Order struct {
+	*instruments.Order
+
+	Stop     instruments.Price
+	Trail    instruments.Price
+	TrailPct float64
+
+	// TIF is how long an order rests; Expires is when a GTD order expires.
+	TIF     TimeInForce
+	Expires time.Time
+
+	// Attached orders are placed once an order is filled,
+	// for no more than the volume that was filled.
+	Attached []*Order
+	// OCO orders are linked to an order so that one cancels the other:
+	// they are reduced by the volume the order fills.
+	OCO []*Order
+
+	filled instruments.Volume
+	// replaced is the volume filled by the orders this order replaced.
+	replaced  instruments.Volume
+	timestamp time.Time
+}
+
+// PartiallyFilled indicates that only part of an order has been transacted.
+const PartiallyFilled = instruments.Cancelled + 1
+
+const (
+	// Stop orders become Market orders once their stop price is reached.
+	Stop = instruments.Limit + 1 + iota
+	// StopLimit orders become Limit orders once their stop price is reached.
+	StopLimit
+	// TrailingStop orders are Stop orders with a stop price
+	// that trails the best quoted price.
+	TrailingStop
+)
+
+// TimeInForce is how long an order rests before it expires.
+type TimeInForce int
+
+const (
+	// GTC orders rest until they are filled or cancelled.
+	GTC TimeInForce = iota // 0
+	// DAY orders expire at the close of the session they are placed in.
+	DAY
+	// IOC orders fill what they can against the current quote,
+	// and the rest is cancelled.
+	IOC
+	// FOK orders fill in full against the current quote, or are cancelled.
+	FOK
+	// GTD orders expire at their Expires timestamp.
+	GTD
+)
+
+// NewOrder instantiates a new order.
+func NewOrder(name string, buy bool, logic instruments.Logic, price instruments.Price, volume instruments.Volume, timestamp time.Time) *Order {
+	return &Order{
+		Order: &instruments.Order{
+			Name: name, Buy: buy, Logic: logic, Status: instruments.Open,
+			QuotedMetric: instruments.QuotedMetric{Price: price, Volume: volume},
+		},
+		timestamp: timestamp,
+	}
+}
+
+// FillOrder instantiates an order for a quoted security, placed as of the quote.
+func FillOrder(quote instruments.Quote, price instruments.Price, volume instruments.Volume, buy bool, logic instruments.Logic) *Order {
+	return NewOrder(quote.Name, buy, logic, price, volume, quote.Timestamp)
+}
+
+// NewStopOrder instantiates an order that becomes a Market order
+// once the quoted price reaches stop.
+func NewStopOrder(name string, buy bool, stop instruments.Price, volume instruments.Volume, timestamp time.Time) *Order {
+	o := NewOrder(name, buy, Stop, stop, volume, timestamp)
+	o.Stop = stop
+	return o
+}
+
+// NewStopLimitOrder instantiates an order that becomes a Limit order at price
+// once the quoted price reaches stop.
+func NewStopLimitOrder(name string, buy bool, stop, price instruments.Price, volume instruments.Volume, timestamp time.Time) *Order {
+	o := NewOrder(name, buy, StopLimit, price, volume, timestamp)
+	o.Stop = stop
+	return o
+}
+
+// NewTrailingStopOrder instantiates a stop order whose stop price
+// follows the best quoted price by a fixed amount.
+func NewTrailingStopOrder(name string, buy bool, trail instruments.Price, volume instruments.Volume, timestamp time.Time) *Order {
+	o := NewOrder(name, buy, TrailingStop, 0, volume, timestamp)
+	o.Trail = trail
+	return o
+}
+
+// NewTrailingStopPctOrder instantiates a stop order whose stop price
+// follows the best quoted price by a percent, given as a fraction of 1.
+func NewTrailingStopPctOrder(name string, buy bool, pct float64, volume instruments.Volume, timestamp time.Time) *Order {
+	o := NewOrder(name, buy, TrailingStop, 0, volume, timestamp)
+	o.TrailPct = pct
+	return o
+}
+
+// NewBracketOrder attaches a take profit Limit order and a protective Stop order
+// to an entry order, each for the entry's volume on the opposite side.
+// The attached orders are one-cancels-other. It returns the entry order.
+func NewBracketOrder(entry *Order, takeProfit, stop instruments.Price) *Order {
+	var profit = NewOrder(entry.Name, !entry.Buy, instruments.Limit, takeProfit, entry.Volume, entry.timestamp)
+	var loss = NewStopOrder(entry.Name, !entry.Buy, stop, entry.Volume, entry.timestamp)
+
+	OneCancelsOther(profit, loss)
+	entry.Attached = []*Order{profit, loss}
+	return entry
+}
+
+// OneCancelsOther links orders so that a fill of one cancels the others.
+// It returns the first order; placing it places them all.
+func OneCancelsOther(orders ...*Order) *Order {
+	if len(orders) == 0 {
+		return nil
+	}
+	for _, o := range orders {
+		o.OCO = make([]*Order, 0, len(orders)-1)
+		for _, other := range orders {
+			if other != o {
+				o.OCO = append(o.OCO, other)
+			}
+		}
+	}
+	return orders[0]
+}
+
+// WithTIF sets the time in force of an order, returning the order.
+func (o *Order) WithTIF(tif TimeInForce) *Order {
+	o.TIF = tif
+	return o
+}
+
+// GoodTill makes an order a GTD order that expires at expires, returning the order.
+func (o *Order) GoodTill(expires time.Time) *Order {
+	o.TIF, o.Expires = GTD, expires
+	return o
+}
+
+// Timestamp returns when an order was placed.
+func (o *Order) Timestamp() time.Time {
+	return o.timestamp
+}
+
+// Filled returns the volume of an order that has been transacted.
+func (o *Order) Filled() instruments.Volume {
+	return o.filled
+}
+
+// Remaining returns the volume of an order that has yet to be transacted.
+func (o *Order) Remaining() instruments.Volume {
+	return o.Volume - o.filled
+}
+
+// Transact a fulfillment of an order at timestamp; yielding a new transaction.
+func (o *Order) Transact(price instruments.Price, volume instruments.Volume, timestamp time.Time) *Transaction {
+	o.filled += volume
+	return &Transaction{Transaction: &instruments.Transaction{
+		Name: o.Name, Buy: o.Buy, Timestamp: timestamp,
+		QuotedMetric: instruments.QuotedMetric{Price: price, Volume: volume},
+	}}
+}
+
+// Transaction represents a fulfillment of an order,
+// along with the costs paid to transact it.
+type Transaction = output.Transaction
@@ -0,0 +1,90 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import "errors"
+
+var ErrNoOrders = errors.New("no orders resting for security")
+
+// OrderBook holds resting orders by security,
+// buys and sells each in the order they were placed.
+type OrderBook struct {
+	buys, sells map[string][]*Order
+}
+
+func NewOrderBook() *OrderBook {
+	return &OrderBook{
+		buys:  make(map[string][]*Order),
+		sells: make(map[string][]*Order),
+	}
+}
+
+func (ob *OrderBook) side(buy bool) map[string][]*Order {
+	if buy {
+		return ob.buys
+	}
+	return ob.sells
+}
+
+// Insert an order into the book.
+func (ob *OrderBook) Insert(order *Order) {
+	var side = ob.side(order.Buy)
+	side[order.Name] = append(side[order.Name], order)
+}
+
+// Remove an order from the book, returning false if it was not in the book.
+func (ob *OrderBook) Remove(order *Order) bool {
+	var side = ob.side(order.Buy)
+	var orders = side[order.Name]
+
+	for i := range orders {
+		if orders[i] != order {
+			continue
+		}
+		if len(orders) == 1 {
+			delete(side, order.Name)
+			return true
+		}
+		side[order.Name] = append(orders[:i:i], orders[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// GetBuys returns the buy orders resting for a security.
+func (ob *OrderBook) GetBuys(name string) ([]*Order, error) {
+	return get(ob.buys, name)
+}
+
+// GetSells returns the sell orders resting for a security.
+func (ob *OrderBook) GetSells(name string) ([]*Order, error) {
+	return get(ob.sells, name)
+}
+
+// get returns a copy of the orders resting for a security,
+// so that orders may be removed from the book while they are ranged over.
+func get(side map[string][]*Order, name string) ([]*Order, error) {
+	var orders = side[name]
+	if len(orders) == 0 {
+		return []*Order{}, ErrNoOrders
+	}
+	return append([]*Order(nil), orders...), nil
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+func TestOrderBook(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	var limit = func(buy bool, price float64) *Order {
+		return NewOrder("AAPL", buy, instruments.Limit, instruments.NewPrice(price), 10, ts)
+	}
+	first, second, sell := limit(true, 9), limit(true, 9.5), limit(false, 11)
+
+	ob := NewOrderBook()
+	for _, order := range []*Order{first, second, sell} {
+		ob.Insert(order)
+	}
+	if buys, err := ob.GetBuys("AAPL"); err != nil || len(buys) != 2 || buys[0] != first {
+		t.Errorf("OrderBook.GetBuys() = %v, %v, want both buys in the order placed", buys, err)
+	}
+	if !ob.Remove(first) || ob.Remove(first) {
+		t.Errorf("OrderBook.Remove() should remove an order only once")
+	}
+	if buys, _ := ob.GetBuys("AAPL"); len(buys) != 1 || buys[0] != second {
+		t.Errorf("OrderBook.GetBuys() = %v, want the second buy", buys)
+	}
+	ob.Remove(second)
+	if _, err := ob.GetBuys("AAPL"); err != ErrNoOrders {
+		t.Errorf("OrderBook.GetBuys() error = %v, want %v", err, ErrNoOrders)
+	}
+	if sells, err := ob.GetSells("AAPL"); err != nil || len(sells) != 1 {
+		t.Errorf("OrderBook.GetSells() = %v, %v, want the sell", sells, err)
+	}
+}
+
+func TestOrder_Transact(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	order := NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10), 10, ts)
+	tx := order.Transact(instruments.NewPrice(10), 4, ts.Add(time.Minute))
+	if order.Filled() != 4 || order.Remaining() != 6 {
+		t.Errorf("Order.Transact() filled, remaining = %v, %v, want 4, 6", order.Filled(), order.Remaining())
+	}
+	if !tx.Timestamp.Equal(ts.Add(time.Minute)) {
+		t.Errorf("Order.Transact() timestamp = %v, want %v", tx.Timestamp, ts.Add(time.Minute))
+	}
+}
@@ -22,111 +22,619 @@ package goat
 
 import (
 	"errors"
+	"time"
 
-	"github.com/jakeschurch/collections"
+	"github.com/jakeschurch/goat/internal/output"
 	"github.com/jakeschurch/instruments"
 )
 
-var ErrLowVolume = errors.New("not enough volueme to fill order")
+var (
+	ErrLowVolume = errors.New("not enough volueme to fill order")
+	ErrLowCash   = errors.New("not enough cash to fill order")
+)
 
+// OrderManager fills orders against a single portfolio,
+// recording results to that portfolio's performance log.
+// Orders rest in the OrderBook until they are filled by quotes;
+// each fill is bounded by the size quoted on the opposite side.
 type OrderManager struct {
-	*collections.OrderBook
+	*OrderBook
+	port *Portfolio
+	log  *output.PerformanceLog
+
+	slippage   SlippageModel
+	commission CommissionModel
+	// borrow is the terms securities are sold short on;
+	// if nil, securities cannot be sold short.
+	borrow *Borrow
+
+	// costMethod selects the lots relieved by sells;
+	// named records the lots specific orders were created for,
+	// and selling records the order working to sell each lot.
+	costMethod CostMethod
+	named      map[*Order][]*instruments.Holding
+	selling    map[*instruments.Holding]*Order
+
+	// margin is the terms the portfolio borrows cash on;
+	// if nil, buys are limited by cash.
+	margin *Margin
+	// accrued is the day fees and interest were last charged.
+	accrued time.Time
+	// close is the time of day sessions close at, and expiry
+	// is when the next resting order expires.
+	close  time.Duration
+	expiry time.Time
+
+	// owners records the algorithm that created each order,
+	// and halted records securities that cannot be traded.
+	owners map[*Order]Algorithm
+	halted map[string]struct{}
+	// groups records the other orders each order is linked one-cancels-other to.
+	groups map[*Order][]*Order
+
+	// resting records the names of securities with orders in the OrderBook.
+	resting map[string]struct{}
+	// quotes records the last quote seen for each security,
+	// less any size that has already been filled against it.
+	quotes map[string]*instruments.Quote
 }
 
-func NewOrderManager() *OrderManager {
+func NewOrderManager(port *Portfolio, log *output.PerformanceLog) *OrderManager {
 	return &OrderManager{
-		OrderBook: collections.NewOrderBook(),
+		OrderBook:  NewOrderBook(),
+		port:       port,
+		log:        log,
+		slippage:   BpsSlippage(0),
+		commission: PerShareCommission(0),
+		named:      make(map[*Order][]*instruments.Holding),
+		selling:    make(map[*instruments.Holding]*Order),
+		close:      defaultSessionClose,
+		owners:     make(map[*Order]Algorithm),
+		halted:     make(map[string]struct{}),
+		groups:     make(map[*Order][]*Order),
+		resting:    make(map[string]struct{}),
+		quotes:     make(map[string]*instruments.Quote),
 	}
 }
 
-func (o *OrderManager) Add(order *instruments.Order) {
-	switch order.Buy {
-	case true:
-		o.Buy(order, Port)
-	case false:
-		o.Sell(order, Port)
+// Add a new order to be managed.
+// IOC and FOK orders are filled against the last quote seen for their security,
+// and cancelled if they cannot be filled, or if no quote has been seen, rather than resting.
+// Market orders are filled against the last quote seen for their security,
+// with any volume left over resting in the OrderBook. If no quote has been
+// seen, other Market orders are filled in full at the order's price.
+// All other orders are placed in the OrderBook to be matched against later quotes.
+// Orders for halted securities, or with invalid prices, are rejected.
+// Orders linked one-cancels-other are placed together, and orders attached
+// to an order are placed once it has been filled.
+func (o *OrderManager) Add(order *Order) {
+	// Place the orders linked to order first; if any fill at once, order may be cancelled.
+	if o.link(order); order.Status != instruments.Open {
+		return
+	}
+	if _, halted := o.halted[order.Name]; halted {
+		o.reject(order, ErrHalted)
+		return
+	}
+	if !validPrice(order) {
+		o.reject(order, ErrInvalidPrice)
+		return
+	}
+	var quote, ok = o.quotes[order.Name]
+
+	if order.Logic == instruments.Market && !ok && order.TIF != IOC && order.TIF != FOK {
+		o.fill(order, order.Price, order.Remaining())
+		return
 	}
 	o.Insert(order)
+	o.resting[order.Name] = struct{}{}
+	o.schedule(order)
+
+	switch order.TIF {
+	case IOC, FOK:
+		if ok && (order.TIF == IOC || fillable(order, quote)) {
+			o.match(order, quote)
+		}
+		if order.Status == instruments.Open || order.Status == PartiallyFilled {
+			o.cancel(order, output.Cancel)
+		}
+	default:
+		if order.Logic == instruments.Market {
+			o.match(order, quote)
+		}
+	}
+	o.prune(order.Name)
+}
+
+// Relieve adds a sell order, naming the lots it should relieve
+// if the SpecificLot cost method is used.
+// Lots are not sold again while the order is working.
+func (o *OrderManager) Relieve(order *Order, lots ...*instruments.Holding) {
+	if o.costMethod == SpecificLot && len(lots) > 0 {
+		o.named[order] = lots
+	}
+	for _, lot := range lots {
+		o.selling[lot] = order
+	}
+	o.Add(order)
 }
 
-func (o *OrderManager) Sell(order *instruments.Order, port *Portfolio) ([]*instruments.Transaction, error) {
-	var TXs = make([]*instruments.Transaction, 0)
-	var sellVol instruments.Volume
+// working reports whether an order is working to sell a lot.
+func (o *OrderManager) working(lot *instruments.Holding) bool {
+	_, ok := o.selling[lot]
+	return ok
+}
 
-	list, err := port.Holdings.Get(order.Name)
-	if err != nil {
-		return TXs, err
+// ForceFill fills an order in full at its price, regardless of quoted size.
+// It is used to close out holdings when a simulation ends,
+// and returns an error if the order was rejected.
+func (o *OrderManager) ForceFill(order *Order) error {
+	_, err := o.fill(order, order.Price, order.Remaining())
+	return err
+}
+
+// Accrue charges borrow fees on short holdings and margin interest on
+// negative cash for every day passed between the last call to Accrue and timestamp.
+func (o *OrderManager) Accrue(timestamp time.Time) {
+	var y, m, d = timestamp.Date()
+	var day = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	if o.accrued.IsZero() {
+		o.accrued = day
+		return
+	}
+	if !day.After(o.accrued) {
+		return
 	}
+	var days = int(day.Sub(o.accrued).Hours() / 24)
+	o.accrued = day
 
-	// If order volume cannot be filled, return error.
-	if list.Volume < order.Volume {
-		return TXs, ErrLowVolume
+	o.chargeBorrow(days)
+	o.chargeInterest(days)
+}
+
+// Match resting orders against a new quote.
+func (o *OrderManager) Match(quote instruments.Quote) {
+	var last = &instruments.Quote{Name: quote.Name, Timestamp: quote.Timestamp}
+	if quote.Bid != nil {
+		bid := *quote.Bid
+		last.Bid = &bid
+	}
+	if quote.Ask != nil {
+		ask := *quote.Ask
+		last.Ask = &ask
 	}
+	o.quotes[quote.Name] = last
+	o.expire(quote.Timestamp)
 
-	list.Lock()
-	// Check to see if we still have holdings
-	x, err := list.Peek()
-	if err != nil {
-		list.Unlock()
-		return TXs, err
+	if _, ok := o.resting[quote.Name]; !ok {
+		return
 	}
-	// Loop over list elements until
-	for x != nil {
-		switch x.Volume < order.Volume {
-		case true:
-			sellVol = x.Volume
-		case false:
-			sellVol = order.Volume
+	if _, halted := o.halted[quote.Name]; halted {
+		return
+	}
+	if buys, err := o.GetBuys(quote.Name); err == nil {
+		for _, order := range buys {
+			o.match(order, last)
 		}
-		// Create new transaction from order.
-		tx := order.Transact(order.Price, sellVol)
+	}
+	if sells, err := o.GetSells(quote.Name); err == nil {
+		for _, order := range sells {
+			o.match(order, last)
+		}
+	}
+	o.prune(quote.Name)
+}
 
-		// Update portfolio's cash value if appropriate.
-		if amt, err := tx.Total(); err == nil {
-			port.cash += amt
+// match a resting order against quote.
+// Stop orders that have been triggered are turned into Market or Limit orders.
+// Buys are filled at the ask if it is at or below the limit price,
+// sells at the bid if it is at or above the limit price;
+// Market orders are filled at whichever is quoted.
+// The volume filled is taken from the quoted size.
+func (o *OrderManager) match(order *Order, quote *instruments.Quote) {
+	if !triggered(order, quote) {
+		return
+	}
+	var price, size = crossed(order, quote)
+	if size == nil {
+		return
+	}
+
+	var volume = order.Remaining()
+	if *size < volume {
+		volume = *size
+	}
+	if volume <= 0 {
+		return
+	}
+	if _, err := o.fill(order, price, volume); err == nil {
+		*size -= volume
+	}
+	if order.Status != PartiallyFilled {
+		o.Remove(order)
+	}
+}
+
+// triggered turns Stop orders that quote has reached the stop price of
+// into Market or Limit orders. It reports whether the order can be filled.
+func triggered(order *Order, quote *instruments.Quote) bool {
+	var bid, ask = quotedPrices(*quote)
+
+	switch order.Logic {
+	case TrailingStop:
+		trail(order, bid, ask)
+		fallthrough
+	case Stop:
+		if !stopped(order, bid, ask) {
+			return false
+		}
+		order.Logic = instruments.Market
+	case StopLimit:
+		if !stopped(order, bid, ask) {
+			return false
 		}
+		order.Logic = instruments.Limit
+	}
+	return true
+}
 
-		// Apply transaction logic to x's Holding.
-		holding, _ := x.Holding.SellOff(*tx)
-		performanceLog.AddHoldings(holding)
+// crossed returns the price an order is filled at against quote,
+// along with the size quoted at that price. Size is nil if the order
+// does not cross the quote.
+func crossed(order *Order, quote *instruments.Quote) (instruments.Price, *instruments.Volume) {
+	var bid, ask = quotedPrices(*quote)
 
-		// Append new tx to TXs slice.
-		TXs = append(TXs, tx)
+	switch order.Buy {
+	case true:
+		if ask == 0 || (order.Logic == instruments.Limit && ask > order.Price) {
+			return 0, nil
+		}
+		return ask, &quote.Ask.Volume
+	default:
+		if bid == 0 || (order.Logic == instruments.Limit && bid < order.Price) {
+			return 0, nil
+		}
+		return bid, &quote.Bid.Volume
+	}
+}
 
-		if x.Volume == 0 {
-			list.Unlock()
-			list.Pop()
+// quotedPrices returns the bid and ask prices of a quote;
+// a side that is not quoted has a price of zero.
+func quotedPrices(quote instruments.Quote) (bid, ask instruments.Price) {
+	if quote.Bid != nil {
+		bid = quote.Bid.Price
+	}
+	if quote.Ask != nil {
+		ask = quote.Ask.Price
+	}
+	return bid, ask
+}
+
+// stopped reports whether a quote has reached an order's stop price.
+func stopped(order *Order, bid, ask instruments.Price) bool {
+	if order.Buy {
+		return ask != 0 && ask >= order.Stop
+	}
+	return bid != 0 && bid <= order.Stop
+}
+
+// trail moves a TrailingStop order's stop price after the best quoted price.
+// Sell stops are only ever raised, and buy stops only ever lowered.
+func trail(order *Order, bid, ask instruments.Price) {
+	var stop instruments.Price
+
+	switch order.Buy {
+	case true:
+		if ask == 0 {
+			return
+		}
+		if stop = ask + order.Trail; order.TrailPct != 0 {
+			stop = ask + instruments.Price(float64(ask)*order.TrailPct)
+		}
+		if order.Stop == 0 || stop < order.Stop {
+			order.Stop = stop
+		}
+	case false:
+		if bid == 0 {
+			return
+		}
+		if stop = bid - order.Trail; order.TrailPct != 0 {
+			stop = bid - instruments.Price(float64(bid)*order.TrailPct)
+		}
+		if stop > order.Stop {
+			order.Stop = stop
 		}
-		x = x.Prev()
 	}
-	list.Unlock()
+}
 
-	return TXs, nil
+// CancelAll resting orders in the OrderBook.
+// Orders attached to a partially filled order are placed as it is
+// cancelled, and are cancelled in turn.
+func (o *OrderManager) CancelAll() {
+	var names = make([]string, 0, len(o.resting))
+	for name := range o.resting {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		for {
+			var orders = make([]*Order, 0)
+
+			if buys, err := o.GetBuys(name); err == nil {
+				orders = append(orders, buys...)
+			}
+			if sells, err := o.GetSells(name); err == nil {
+				orders = append(orders, sells...)
+			}
+			if len(orders) == 0 {
+				break
+			}
+			for _, order := range orders {
+				o.cancel(order, output.Cancel)
+			}
+		}
+		delete(o.resting, name)
+	}
+}
+
+// prune name from resting if no orders are left in the OrderBook for it.
+func (o *OrderManager) prune(name string) {
+	var _, buyErr = o.GetBuys(name)
+	var _, sellErr = o.GetSells(name)
+
+	if buyErr != nil && sellErr != nil {
+		delete(o.resting, name)
+	}
 }
 
-func (o *OrderManager) Buy(order *instruments.Order, port *Portfolio) ([]*instruments.Transaction, error) {
-	var TXs = make([]*instruments.Transaction, 0)
-	var orderAmt, err = order.Total()
+// fill volume of an order at price. If the order cannot be filled, it is rejected.
+func (o *OrderManager) fill(order *Order, price instruments.Price, volume instruments.Volume) ([]*Transaction, error) {
+	var TXs []*Transaction
+	var err error
+
+	switch order.Buy {
+	case true:
+		TXs, err = o.Buy(order, price, volume)
+	case false:
+		TXs, err = o.Sell(order, price, volume)
+	}
 	if err != nil {
+		o.reject(order, err)
+		return TXs, err
+	}
+	o.log.AddTransactions(TXs...)
+	o.reduce(order, volume)
+	if l, ok := o.listener(order); ok {
+		for _, tx := range TXs {
+			l.OnFill(tx)
+		}
+	}
+	if order.Remaining() > 0 {
+		order.Status = PartiallyFilled
+		return TXs, nil
+	}
+	order.Status = instruments.Closed
+	o.done(order)
+	o.log.AddOrders(order.Order)
+	return TXs, nil
+}
+
+// Sell to fill volume of an order at price.
+// Long holdings are sold off first; any volume left over is sold short
+// if shorting is allowed, otherwise the order cannot be filled.
+func (o *OrderManager) Sell(order *Order, price instruments.Price, volume instruments.Volume) ([]*Transaction, error) {
+	var TXs = make([]*Transaction, 0)
+
+	var held = o.held(order.Name)
+	if held < volume {
+		var opening = volume
+		if held > 0 {
+			opening -= held
+		}
+		if err := o.borrow.Locate(order.Name); err != nil {
+			return TXs, err
+		}
+		if err := o.checkMargin(order, price, opening); err != nil {
+			return TXs, err
+		}
+	}
+	if held > 0 {
+		var sold = volume
+		if held < sold {
+			sold = held
+		}
+		txs, err := o.relieve(order, price, sold)
+		TXs = append(TXs, txs...)
+		if err != nil {
+			return TXs, err
+		}
+		volume -= sold
+	}
+	if volume > 0 {
+		TXs = append(TXs, o.short(order, price, volume))
+	}
+	return TXs, nil
+}
+
+// Buy to fill volume of an order at price.
+// Short holdings are covered first; any volume left over is bought as a new holding.
+func (o *OrderManager) Buy(order *Order, price instruments.Price, volume instruments.Volume) ([]*Transaction, error) {
+	var TXs = make([]*Transaction, 0)
+	var fillPrice = o.slippage.Slip(true, price, volume)
+	var orderAmt = instruments.NewAmount(fillPrice, volume)
+
+	var covered instruments.Volume
+	if held := o.held(order.Name); held < 0 {
+		covered = volume
+		if -held < covered {
+			covered = -held
+		}
+	}
+
+	// If order cannot be paid for, return error.
+	if orderAmt == 0 {
+		return TXs, instruments.ErrZeroValue
+	}
+	// Covering a short is not limited by cash, so that a losing short
+	// can always be closed out; only new holdings must be paid for.
+	var bought = instruments.NewAmount(fillPrice, volume-covered)
+	if o.margin == nil && bought > 0 && o.port.cash < bought+o.estimate(order, fillPrice, volume) {
+		return TXs, ErrLowCash
+	}
+	if err := o.checkMargin(order, price, volume-covered); err != nil {
 		return TXs, err
 	}
-	// If order volume cannot be filled, return error.
-	if port.cash > orderAmt {
-		return TXs, ErrLowVolume
+
+	if covered > 0 {
+		txs, err := o.relieve(order, price, covered)
+		TXs = append(TXs, txs...)
+		if err != nil {
+			return TXs, err
+		}
+		volume -= covered
+	}
+	if volume == 0 {
+		return TXs, nil
 	}
 
 	// Create new transaction from order.
-	tx := order.Transact(order.Price, order.Volume)
+	tx := o.transact(order, price, volume)
 
 	// Update portfolio's cash value if appropriate.
 	if amt, err := tx.Total(); err == nil {
-		port.cash -= amt
+		o.port.cash -= amt + tx.Commission
 	}
 	// Apply transaction logic to buy NewHolding.
-	h, _ := instruments.Buy(*tx)
-	port.Insert(*h)
+	h, _ := instruments.Buy(*tx.Transaction)
+	o.port.Insert(*h)
 	// Append new tx to TXs slice.
 	TXs = append(TXs, tx)
-	performanceLog.AddOrders(order)
 	return TXs, nil
 }
+
+// checkMargin checks that the portfolio has the buying power to open
+// volume of new positions at price. Without a margin account, only
+// shorts are checked, and they must be fully covered by equity.
+func (o *OrderManager) checkMargin(order *Order, price instruments.Price, volume instruments.Volume) error {
+	var m = o.margin
+	if m == nil {
+		if order.Buy {
+			return nil
+		}
+		m = cashAccount
+	}
+	if volume <= 0 {
+		return nil
+	}
+	var amt = instruments.NewAmount(o.slippage.Slip(order.Buy, price, volume), volume)
+	return m.Check(o.port.Equity(time.Time{}), amt)
+}
+
+// held returns the volume of a security held, which is negative if held short.
+func (o *OrderManager) held(name string) instruments.Volume {
+	list, err := o.port.Holdings.Get(name)
+	if err != nil {
+		return 0
+	}
+	return list.Volume
+}
+
+// relieve volume of held lots to fill an order at price.
+// Sells relieve long holdings and buys cover short holdings,
+// in the order given by the cost method; the realized portion
+// of each lot is recorded to the performance log.
+func (o *OrderManager) relieve(order *Order, price instruments.Price, volume instruments.Volume) ([]*Transaction, error) {
+	var TXs = make([]*Transaction, 0)
+
+	list, err := o.port.Holdings.Get(order.Name)
+	if err != nil {
+		return TXs, err
+	}
+	holdings, err := o.port.GetSlice(order.Name)
+	if err != nil {
+		return TXs, err
+	}
+
+	if o.costMethod == AvgCost {
+		averageCost(holdings)
+	}
+
+	var toRelieve = volume
+	for _, x := range reliefOrder(o.costMethod, order.Buy, holdings, o.named[order]...) {
+		if toRelieve == 0 {
+			break
+		}
+		var lotVol = x.Volume
+		if lotVol < 0 {
+			lotVol = -lotVol
+		}
+		if lotVol > toRelieve {
+			lotVol = toRelieve
+		}
+		// Create new transaction from order.
+		tx := o.transact(order, price, lotVol)
+		var fill = instruments.TxMetric{Price: tx.Price, Date: tx.Timestamp}
+		var bought, sold = x.Buy, fill
+
+		// Update portfolio's cash value, and apply transaction logic to x's Holding.
+		amt, _ := tx.Total()
+		switch order.Buy {
+		case true:
+			o.port.cash -= amt + tx.Commission
+			x.Volume += lotVol
+			list.Volume += lotVol
+			bought, sold = fill, x.Buy
+		case false:
+			o.port.cash += amt - tx.Commission
+			if _, err := x.SellOff(*tx.Transaction); err != nil {
+				return TXs, err
+			}
+			list.Volume -= lotVol
+		}
+		// Record the portion of x that was relieved.
+		o.log.AddHoldings(&instruments.Holding{
+			Name: x.Name, Volume: lotVol, Buy: bought, Sell: sold,
+		})
+		o.log.AddLots(output.NewLot(instruments.Holding{Name: x.Name, Buy: bought}, lotVol, sold))
+		toRelieve -= lotVol
+
+		// Append new tx to TXs slice.
+		TXs = append(TXs, tx)
+	}
+	if list.Volume == 0 {
+		o.port.Remove(order.Name)
+	}
+	return TXs, nil
+}
+
+// estimate the commission charged to fill volume of an order at price.
+func (o *OrderManager) estimate(order *Order, price instruments.Price, volume instruments.Volume) instruments.Amount {
+	var tx = &Transaction{Transaction: &instruments.Transaction{
+		Name: order.Name, Buy: order.Buy, QuotedMetric: instruments.QuotedMetric{Price: price, Volume: volume},
+	}}
+	if oc, ok := o.commission.(OrderCommissionModel); ok {
+		return oc.Estimate(order, tx)
+	}
+	return o.commission.Commission(order, tx)
+}
+
+// transact volume of an order at a quoted price,
+// recording the slippage and commission paid on the transaction.
+// Transactions are dated by the last quote seen for the order's security,
+// or by the order if none has been seen.
+func (o *OrderManager) transact(order *Order, price instruments.Price, volume instruments.Volume) *Transaction {
+	var fillPrice = o.slippage.Slip(order.Buy, price, volume)
+	var timestamp = o.now(order.Name)
+	if timestamp.IsZero() {
+		timestamp = order.Timestamp()
+	}
+
+	tx := order.Transact(fillPrice, volume, timestamp)
+	if order.Buy {
+		tx.Slippage = instruments.NewAmount(fillPrice-price, volume)
+	} else {
+		tx.Slippage = instruments.NewAmount(price-fillPrice, volume)
+	}
+	tx.Commission = o.commission.Commission(order, tx)
+	return tx
+}
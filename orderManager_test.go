@@ -0,0 +1,238 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+func mockOrderManager(cash float64) *OrderManager {
+	port := NewPortfolio(instruments.NewAmount(instruments.NewPrice(1.00), instruments.NewVolume(cash)))
+	return NewOrderManager(port, output.NewPerformanceLog())
+}
+
+func mockQuote(name string, bid, ask float64) instruments.Quote {
+	return instruments.Quote{
+		Name:      name,
+		Bid:       instruments.NewQuotedMetric(bid, 100),
+		Ask:       instruments.NewQuotedMetric(ask, 100),
+		Timestamp: time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestOrderManager_Match(t *testing.T) {
+	tests := []struct {
+		name       string
+		buy        bool
+		limit      float64
+		quote      instruments.Quote
+		wantStatus instruments.Status
+		wantVolume instruments.Volume
+	}{
+		{"buy not crossed", true, 10.00, mockQuote("AAPL", 10.05, 10.10), instruments.Open, 0},
+		{"buy crossed", true, 10.00, mockQuote("AAPL", 9.95, 10.00), instruments.Closed, 10},
+		{"buy other security", true, 10.00, mockQuote("MSFT", 9.95, 10.00), instruments.Open, 0},
+		{"sell not crossed", false, 11.00, mockQuote("AAPL", 10.95, 11.05), instruments.Open, 10},
+		{"sell crossed", false, 11.00, mockQuote("AAPL", 11.05, 11.10), instruments.Closed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			if !tt.buy {
+				om.Add(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 10, tt.quote.Timestamp))
+			}
+			order := NewOrder("AAPL", tt.buy, instruments.Limit, instruments.NewPrice(tt.limit), 10, tt.quote.Timestamp)
+			om.Add(order)
+			om.Match(tt.quote)
+
+			if order.Status != tt.wantStatus {
+				t.Errorf("OrderManager.Match() status = %v, want %v", order.Status, tt.wantStatus)
+			}
+			var gotVolume instruments.Volume
+			if list, err := om.port.Holdings.Get("AAPL"); err == nil {
+				gotVolume = list.Volume
+			}
+			if gotVolume != tt.wantVolume {
+				t.Errorf("OrderManager.Match() volume held = %v, want %v", gotVolume, tt.wantVolume)
+			}
+		})
+	}
+}
+
+func TestOrderManager_CancelAll(t *testing.T) {
+	om := mockOrderManager(1000)
+	order := NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(10.00), 10, time.Now())
+	om.Add(order)
+	om.CancelAll()
+
+	if order.Status != instruments.Cancelled {
+		t.Errorf("OrderManager.CancelAll() status = %v, want %v", order.Status, instruments.Cancelled)
+	}
+	if _, err := om.GetBuys("AAPL"); err == nil {
+		t.Errorf("OrderManager.CancelAll() left orders in OrderBook")
+	}
+	om.Match(mockQuote("AAPL", 9.00, 9.50))
+	if order.Status != instruments.Cancelled {
+		t.Errorf("OrderManager.Match() filled a cancelled order")
+	}
+}
+
+func TestOrderManager_CancelAll_attached(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	om := mockOrderManager(1000)
+	entry := NewBracketOrder(NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(10), 10, ts), instruments.NewPrice(12), instruments.NewPrice(8))
+	om.Add(entry)
+	om.Match(instruments.Quote{
+		Name: "AAPL", Timestamp: ts,
+		Bid: instruments.NewQuotedMetric(9.95, 100), Ask: instruments.NewQuotedMetric(10.00, 4),
+	})
+	om.CancelAll()
+
+	if sells, err := om.GetSells("AAPL"); err == nil {
+		t.Errorf("OrderManager.CancelAll() left attached orders %v in OrderBook", sells)
+	}
+	if len(om.resting) != 0 {
+		t.Errorf("OrderManager.CancelAll() resting = %v, want none", om.resting)
+	}
+}
+
+func TestOrderManager_Match_timestamp(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	om := mockOrderManager(1000)
+	om.Add(NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(10.00), 10, ts))
+
+	quote := mockQuote("AAPL", 9.95, 10.00)
+	quote.Timestamp = ts.Add(time.Hour)
+	om.Match(quote)
+
+	holdings, err := om.port.GetSlice("AAPL")
+	if err != nil || len(holdings) != 1 {
+		t.Fatalf("Portfolio.GetSlice() = %v, %v, want one holding", holdings, err)
+	}
+	if got := holdings[0].Buy.Date; !got.Equal(quote.Timestamp) {
+		t.Errorf("OrderManager.Match() bought at %v, want %v", got, quote.Timestamp)
+	}
+}
+
+func TestOrderManager_Match_stops(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		order      *Order
+		quotes     []instruments.Quote
+		wantStatus instruments.Status
+		wantLogic  instruments.Logic
+		wantStop   instruments.Price
+	}{
+		{"sell stop not triggered", NewStopOrder("AAPL", false, instruments.NewPrice(9.50), 10, ts),
+			[]instruments.Quote{mockQuote("AAPL", 9.55, 9.60)}, instruments.Open, Stop, instruments.NewPrice(9.50)},
+		{"sell stop triggered", NewStopOrder("AAPL", false, instruments.NewPrice(9.50), 10, ts),
+			[]instruments.Quote{mockQuote("AAPL", 9.50, 9.55)}, instruments.Closed, instruments.Market, instruments.NewPrice(9.50)},
+		{"buy stop triggered", NewStopOrder("AAPL", true, instruments.NewPrice(10.50), 10, ts),
+			[]instruments.Quote{mockQuote("AAPL", 10.45, 10.50)}, instruments.Closed, instruments.Market, instruments.NewPrice(10.50)},
+		{"sell stop limit triggered not crossed", NewStopLimitOrder("AAPL", false, instruments.NewPrice(9.50), instruments.NewPrice(9.45), 10, ts),
+			[]instruments.Quote{mockQuote("AAPL", 9.40, 9.45)}, instruments.Open, instruments.Limit, instruments.NewPrice(9.50)},
+		{"sell stop limit triggered crossed", NewStopLimitOrder("AAPL", false, instruments.NewPrice(9.50), instruments.NewPrice(9.45), 10, ts),
+			[]instruments.Quote{mockQuote("AAPL", 9.40, 9.45), mockQuote("AAPL", 9.45, 9.50)}, instruments.Closed, instruments.Limit, instruments.NewPrice(9.50)},
+		{"sell trailing stop follows bid", NewTrailingStopOrder("AAPL", false, instruments.NewPrice(0.50), 10, ts),
+			[]instruments.Quote{mockQuote("AAPL", 10.00, 10.05), mockQuote("AAPL", 11.00, 11.05), mockQuote("AAPL", 10.60, 10.65)},
+			instruments.Open, TrailingStop, instruments.NewPrice(10.50)},
+		{"sell trailing stop triggered", NewTrailingStopOrder("AAPL", false, instruments.NewPrice(0.50), 10, ts),
+			[]instruments.Quote{mockQuote("AAPL", 11.00, 11.05), mockQuote("AAPL", 10.50, 10.55)},
+			instruments.Closed, instruments.Market, instruments.NewPrice(10.50)},
+		{"buy trailing stop percent", NewTrailingStopPctOrder("AAPL", true, 0.10, 10, ts),
+			[]instruments.Quote{mockQuote("AAPL", 19.95, 20.00), mockQuote("AAPL", 9.95, 10.00), mockQuote("AAPL", 10.85, 10.90)},
+			instruments.Open, TrailingStop, instruments.NewPrice(11.00)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			om.Add(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 10, ts))
+			om.Add(tt.order)
+			for _, quote := range tt.quotes {
+				om.Match(quote)
+			}
+			if tt.order.Status != tt.wantStatus {
+				t.Errorf("OrderManager.Match() status = %v, want %v", tt.order.Status, tt.wantStatus)
+			}
+			if tt.order.Logic != tt.wantLogic {
+				t.Errorf("OrderManager.Match() logic = %v, want %v", tt.order.Logic, tt.wantLogic)
+			}
+			if tt.order.Stop != tt.wantStop {
+				t.Errorf("OrderManager.Match() stop = %v, want %v", tt.order.Stop, tt.wantStop)
+			}
+		})
+	}
+}
+
+func TestOrderManager_Match_partialFills(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	var sized = func(bid, ask float64, bidSz, askSz float64) instruments.Quote {
+		return instruments.Quote{
+			Name: "AAPL", Timestamp: ts,
+			Bid: instruments.NewQuotedMetric(bid, bidSz), Ask: instruments.NewQuotedMetric(ask, askSz),
+		}
+	}
+	tests := []struct {
+		name       string
+		order      *Order
+		quotes     []instruments.Quote
+		wantStatus instruments.Status
+		wantFilled instruments.Volume
+	}{
+		{"market buy capped by ask size", NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 100, ts),
+			[]instruments.Quote{sized(9.95, 10.00, 100, 30)}, PartiallyFilled, 30},
+		{"market buy filled over quotes", NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 100, ts),
+			[]instruments.Quote{sized(9.95, 10.00, 100, 30), sized(9.95, 10.00, 100, 80)}, instruments.Closed, 100},
+		{"limit buy capped by ask size", NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(10.00), 100, ts),
+			[]instruments.Quote{sized(9.90, 9.95, 100, 1), sized(9.90, 10.05, 100, 500)}, PartiallyFilled, 1},
+		{"limit sell capped by bid size", NewOrder("AAPL", false, instruments.Limit, instruments.NewPrice(10.00), 50, ts),
+			[]instruments.Quote{sized(10.05, 10.10, 20, 100), sized(10.00, 10.05, 40, 100)}, instruments.Closed, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(10000)
+			if !tt.order.Buy {
+				om.Add(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 100, ts))
+			}
+			if tt.order.Logic == instruments.Market {
+				om.Match(tt.quotes[0])
+				om.Add(tt.order)
+				tt.quotes = tt.quotes[1:]
+			} else {
+				om.Add(tt.order)
+			}
+			for _, quote := range tt.quotes {
+				om.Match(quote)
+			}
+			if tt.order.Status != tt.wantStatus {
+				t.Errorf("OrderManager.Match() status = %v, want %v", tt.order.Status, tt.wantStatus)
+			}
+			if tt.order.Filled() != tt.wantFilled {
+				t.Errorf("OrderManager.Match() filled = %v, want %v", tt.order.Filled(), tt.wantFilled)
+			}
+		})
+	}
+}
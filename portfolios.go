@@ -21,8 +21,14 @@
 package goat
 
 import (
+	"encoding/csv"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/jakeschurch/goat/internal/output"
 	"github.com/jakeschurch/instruments"
 
 	"github.com/jakeschurch/collections"
@@ -41,55 +47,263 @@ func NewPortfolio(cash instruments.Amount) *Portfolio {
 	}
 }
 
-func (p *Portfolio) Update(quote instruments.Quote, algos ...Algorithm) {
+// Insert a holding into the portfolio.
+func (p *Portfolio) Insert(holding instruments.Holding) {
+	insert(p.Portfolio, holding)
+}
+
+// insert a holding into a portfolio. The vendored holdings list counts the
+// volume of the first holding of a security twice, so the extra is taken back out.
+func insert(p *collections.Portfolio, holding instruments.Holding) {
+	var _, err = p.Holdings.Get(holding.Name)
+	p.Insert(holding)
+	if err == nil {
+		return
+	}
+	if list, err := p.Holdings.Get(holding.Name); err == nil {
+		list.Volume -= holding.Volume
+	}
+}
+
+// Update holdings from quoted data, and hand any sell orders
+// created by algos to the order manager.
+func (p *Portfolio) Update(quote instruments.Quote, om *OrderManager, algos ...Algorithm) {
 	p.Holdings.Update(quote)
-	if orders, err := p.checkSells(quote, algos...); err == nil {
-		for i := range orders {
-			orderManager.Add(orders[i])
+	if sells, err := p.checkSells(quote, om, algos...); err == nil {
+		for _, sell := range sells {
+			om.Listen(sell.Order, sell.algo)
+			om.Relieve(sell.Order, sell.lot)
+		}
+	}
+}
+
+// Equity marks a portfolio's holdings to market at their last quoted bid,
+// and short holdings at their last quoted ask.
+func (p *Portfolio) Equity(timestamp time.Time) output.Equity {
+	var e = output.Equity{Timestamp: timestamp, Cash: p.cash}
+
+	for k := range p.Holdings.Keys() {
+		value, ok := p.value(k)
+		if !ok {
+			continue
+		}
+		e.MarketValue += value
+		e.NetExposure += value
+		if value < 0 {
+			value = -value
 		}
+		e.GrossExposure += value
 	}
+	e.Equity = e.Cash + e.MarketValue
+	return e
+}
+
+// value marks holdings of a security to market, returning false if none are held.
+func (p *Portfolio) value(name string) (instruments.Amount, bool) {
+	var list, err = p.Holdings.Get(name)
+	if err != nil || list.Volume == 0 {
+		return 0, false
+	}
+	var price = list.LastBid.Price
+	if list.Volume < 0 {
+		price = list.LastAsk.Price
+	}
+	return instruments.NewAmount(price, list.Volume), true
+}
+
+// closeOrder returns a Market order that closes out holdings of a security.
+// Long holdings are sold at their last quoted bid,
+// and short holdings are covered at their last quoted ask.
+func (p *Portfolio) closeOrder(name string) (*Order, error) {
+	var list, err = p.Holdings.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case list.Volume < 0:
+		return NewOrder(list.Name, true, instruments.Market, list.LastAsk.Price, -list.Volume, list.LastAsk.Date), nil
+	case list.Volume > 0:
+		return NewOrder(list.Name, false, instruments.Market, list.LastBid.Price, list.Volume, list.LastBid.Date), nil
+	}
+	return nil, nil
+}
+
+// largest returns the name of the security with the greatest gross market value,
+// along with an order that closes it out. Securities in skip are passed over.
+func (p *Portfolio) largest(skip map[string]bool) (string, *Order) {
+	var name string
+	var max instruments.Amount
+
+	for k := range p.Holdings.Keys() {
+		value, ok := p.value(k)
+		if !ok || skip[k] {
+			continue
+		}
+		if value < 0 {
+			value = -value
+		}
+		if name == "" || value > max {
+			name, max = k, value
+		}
+	}
+	if name == "" {
+		return name, nil
+	}
+	order, _ := p.closeOrder(name)
+	return name, order
 }
 
 // CloseAll Open Holdings in Portfolio instance.
-func (p *Portfolio) CloseAll() error {
-	for k, _ := range p.Holdings.Keys() {
-		var list, err = p.Holdings.Get(k)
+// Long holdings are sold, and short holdings are covered.
+// An error is returned if a holding could not be closed.
+func (p *Portfolio) CloseAll(om *OrderManager) error {
+	var keys = make([]string, 0, len(p.Holdings.Keys()))
+	for k := range p.Holdings.Keys() {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		var order, err = p.closeOrder(k)
 		if err != nil {
 			return err
 		}
-		orderManager.Add(instruments.NewOrder(list.Name, false, instruments.Market, list.LastAsk.Price, list.Volume, list.LastAsk.Date))
+		if order == nil {
+			continue
+		}
+		if err = om.ForceFill(order); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// sellOrder is an order created by an algorithm's Sell method,
+// along with the holding it was created for.
+type sellOrder struct {
+	*Order
+	lot  *instruments.Holding
+	algo Algorithm
+}
+
 // checkSells to see if we can create an orders.
 // if holdings are empty GetSlice will return error.
-func (p *Portfolio) checkSells(quote instruments.Quote, algos ...Algorithm) ([]*instruments.Order, error) {
-	var sells = make([]*instruments.Order, 0)
+// Holdings with a sell order still working in om are not sold again.
+func (p *Portfolio) checkSells(quote instruments.Quote, om *OrderManager, algos ...Algorithm) ([]sellOrder, error) {
+	var sells = make([]sellOrder, 0)
 	var holdings, err = p.GetSlice(quote.Name)
 	if err != nil {
 		return sells, err
 	}
 
-	var i, j = 0, 0
-	for j <= len(algos) {
-		if order, ok := algos[j].Sell(quote, holdings[i]); ok {
-			sells = append(sells, order)
-		}
-		if i < len(holdings) {
-			i++
-		}
-		if j < len(algos) {
-			j++
-			i = 0
-		} else {
-			break
+	var selling = make(map[*instruments.Holding]bool)
+	for _, algo := range algos {
+		for _, holding := range holdings {
+			// Holdings that have been sold off are left in place until
+			// all holdings of a security are sold off.
+			if holding.Volume == 0 || selling[holding] || om.working(holding) {
+				continue
+			}
+			if order, ok := algo.Sell(quote, holding); ok {
+				sells = append(sells, sellOrder{order, holding, algo})
+				selling[holding] = true
+			}
 		}
 	}
 	return sells, nil
 }
 
+// Benchmark is a portfolio that buys and holds a single security
+// with the same starting cash as a simulation's Portfolio.
 type Benchmark struct {
 	*collections.Portfolio
 	sync.RWMutex
+	Name string
+	cash instruments.Amount
+}
+
+func NewBenchmark(name string, cash instruments.Amount) *Benchmark {
+	return &Benchmark{
+		Portfolio: collections.NewPortfolio(),
+		Name:      name,
+		cash:      cash,
+	}
+}
+
+// Update the benchmark from a quote of its security.
+// The first quote with an ask buys as much of the security as cash allows.
+func (b *Benchmark) Update(quote instruments.Quote) {
+	if quote.Name != b.Name {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+
+	if _, err := b.Holdings.Get(b.Name); err != nil {
+		if quote.Ask == nil || quote.Ask.Price == 0 {
+			return
+		}
+		volume := instruments.Volume(b.cash / instruments.Amount(quote.Ask.Price))
+		if volume == 0 {
+			return
+		}
+		b.cash -= instruments.NewAmount(quote.Ask.Price, volume)
+		insert(b.Portfolio, instruments.Holding{
+			Name: b.Name, Volume: volume,
+			Buy: instruments.TxMetric{Price: quote.Ask.Price, Date: quote.Timestamp},
+		})
+	}
+	b.Holdings.Update(quote)
+}
+
+// Value marks the benchmark to market at its last quoted bid.
+func (b *Benchmark) Value() instruments.Amount {
+	b.RLock()
+	defer b.RUnlock()
+
+	list, err := b.Holdings.Get(b.Name)
+	if err != nil {
+		return b.cash
+	}
+	return b.cash + instruments.NewAmount(list.LastBid.Price, list.Volume)
+}
+
+// readBenchmarkPrices reads a file of benchmark prices as quotes of name.
+// Each line of the file holds an RFC 3339 timestamp and a price, separated
+// by a comma; lines that cannot be parsed, such as headers, are skipped.
+// Compressed files are decompressed as they are read.
+func readBenchmarkPrices(name, path string) ([]instruments.Quote, error) {
+	var quotes = make([]instruments.Quote, 0)
+
+	file, err := openQuotes(path)
+	if err != nil {
+		return quotes, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return quotes, err
+	}
+	for _, record := range records {
+		if len(record) < 2 {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(record[0]))
+		if err != nil {
+			continue
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			continue
+		}
+		quotes = append(quotes, instruments.Quote{
+			Name: name, Timestamp: timestamp,
+			Bid: instruments.NewQuotedMetric(price, 0), Ask: instruments.NewQuotedMetric(price, 0),
+		})
+	}
+	sort.SliceStable(quotes, func(i, j int) bool {
+		return quotes[i].Timestamp.Before(quotes[j].Timestamp)
+	})
+	return quotes, nil
 }
@@ -0,0 +1,107 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+func TestBenchmark_Update(t *testing.T) {
+	var cash = instruments.NewAmount(instruments.NewPrice(1.00), 1000)
+	tests := []struct {
+		name   string
+		quotes []instruments.Quote
+		want   instruments.Amount
+	}{
+		{"no quotes", nil, cash},
+		{"other security", []instruments.Quote{mockQuote("MSFT", 9.00, 9.00)}, cash},
+		{"bought", []instruments.Quote{mockQuote("SPY", 29.95, 30.00)}, instruments.NewAmount(instruments.NewPrice(10.00), 1) + instruments.NewAmount(instruments.NewPrice(29.95), 33)},
+		{"marked to market", []instruments.Quote{mockQuote("SPY", 29.95, 30.00), mockQuote("SPY", 33.00, 33.05)},
+			instruments.NewAmount(instruments.NewPrice(10.00), 1) + instruments.NewAmount(instruments.NewPrice(33.00), 33)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBenchmark("SPY", cash)
+			for _, quote := range tt.quotes {
+				b.Update(quote)
+			}
+			if got := b.Value(); got != tt.want {
+				t.Errorf("Benchmark.Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readBenchmarkPrices(t *testing.T) {
+	file, err := ioutil.TempFile("", "benchmark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("timestamp,price\n2017-08-15T09:30:00Z,245.10\n2017-08-14T09:30:00Z,244.50\n")
+	file.Close()
+
+	got, err := readBenchmarkPrices("SPY", file.Name())
+	if err != nil {
+		t.Fatalf("readBenchmarkPrices() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("readBenchmarkPrices() read %v prices, want 2", len(got))
+	}
+	if want := time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC); !got[0].Timestamp.Equal(want) || got[0].Bid.Price != instruments.NewPrice(244.50) {
+		t.Errorf("readBenchmarkPrices() first = %v %v, want %v %v", got[0].Timestamp, got[0].Bid.Price, want, instruments.NewPrice(244.50))
+	}
+}
+
+// sellAll is a long-only algorithm that sells every lot it holds at market.
+type sellAll struct{ Algorithm_Example }
+
+func (sellAll) Sell(quote instruments.Quote, holding *instruments.Holding) (*Order, bool) {
+	return NewOrder(quote.Name, false, instruments.Market, quote.Bid.Price, holding.Volume, quote.Timestamp), true
+}
+
+func TestPortfolio_Update_working(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	om := mockOrderManager(10000)
+	om.borrow = &Borrow{}
+	om.Add(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 70, ts))
+
+	for i := 0; i < 5; i++ {
+		quote := instruments.Quote{
+			Name: "AAPL", Timestamp: ts.Add(time.Duration(i) * time.Minute),
+			Bid: instruments.NewQuotedMetric(9.95, 30), Ask: instruments.NewQuotedMetric(10.00, 30),
+		}
+		om.Match(quote)
+		om.port.Update(quote, om, sellAll{})
+
+		if got := om.held("AAPL"); got < 0 {
+			t.Fatalf("Portfolio.Update() held = %v after quote %d, want a long-only algo never to go short", got, i)
+		}
+	}
+	if got := om.held("AAPL"); got != 0 {
+		t.Errorf("Portfolio.Update() held = %v, want 0", got)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"errors"
+	"math"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/instruments"
+)
+
+var ErrHardToBorrow = errors.New("security is hard to borrow")
+
+// Borrow is the terms that securities are borrowed on to be sold short.
+//
+// Short holdings are held with a negative volume, and their Buy metric
+// records the price and date they were sold short at. They are covered
+// by buy orders, which can be created by an Algorithm's Sell method.
+type Borrow struct {
+	// Rate is the annual fee charged on the market value of short holdings,
+	// given as a fraction of 1. Fees accrue daily over a 360 day year.
+	Rate float64
+	// HardToBorrow securities cannot be sold short.
+	HardToBorrow map[string]struct{}
+}
+
+// NewBorrow returns the borrow terms set in a config,
+// or nil if short selling is not allowed.
+func NewBorrow(c config.Config) *Borrow {
+	if !c.Backtest.AllowShort {
+		return nil
+	}
+	var b = &Borrow{
+		Rate:         c.Backtest.BorrowRate,
+		HardToBorrow: make(map[string]struct{}),
+	}
+	for _, name := range c.Backtest.HardToBorrow {
+		b.HardToBorrow[name] = struct{}{}
+	}
+	return b
+}
+
+// Locate checks that a security can be borrowed to sell short.
+func (b *Borrow) Locate(name string) error {
+	if b == nil {
+		return ErrLowVolume
+	}
+	if _, ok := b.HardToBorrow[name]; ok {
+		return ErrHardToBorrow
+	}
+	return nil
+}
+
+// short sells volume of an order at price, opening a short holding.
+func (o *OrderManager) short(order *Order, price instruments.Price, volume instruments.Volume) *Transaction {
+	tx := o.transact(order, price, volume)
+
+	if amt, err := tx.Total(); err == nil {
+		o.port.cash += amt - tx.Commission
+	}
+	o.port.Insert(instruments.Holding{
+		Name: order.Name, Volume: -volume,
+		Buy: instruments.TxMetric{Price: tx.Price, Date: tx.Timestamp},
+	})
+	return tx
+}
+
+// chargeBorrow fees on short holdings for a number of days.
+// Short holdings are valued at their last quoted ask.
+func (o *OrderManager) chargeBorrow(days int) {
+	if o.borrow == nil || o.borrow.Rate == 0 {
+		return
+	}
+	for name := range o.port.Holdings.Keys() {
+		list, err := o.port.Holdings.Get(name)
+		if err != nil || list.Volume >= 0 {
+			continue
+		}
+		var value = instruments.NewAmount(list.LastAsk.Price, -list.Volume)
+		var fee = instruments.Amount(math.Round(float64(value) * o.borrow.Rate / 360 * float64(days)))
+
+		o.port.cash -= fee
+		o.log.AddBorrowFees(name, fee)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/instruments"
+)
+
+func TestOrderManager_Sell_short(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		borrow     *Borrow
+		volume     instruments.Volume
+		wantStatus instruments.Status
+		wantHeld   instruments.Volume
+		wantCash   instruments.Amount
+	}{
+		{"shorts not allowed", nil, 10, instruments.Cancelled, 0, 100000},
+		{"hard to borrow", &Borrow{HardToBorrow: map[string]struct{}{"AAPL": {}}}, 10, instruments.Cancelled, 0, 100000},
+		{"short", &Borrow{}, 10, instruments.Closed, -10, 120000},
+		{"short more than equity", &Borrow{}, 1000000, instruments.Cancelled, 0, 100000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			om.borrow = tt.borrow
+			order := NewOrder("AAPL", false, instruments.Market, instruments.NewPrice(20), tt.volume, ts)
+			om.Add(order)
+
+			if order.Status != tt.wantStatus {
+				t.Errorf("OrderManager.Sell() status = %v, want %v", order.Status, tt.wantStatus)
+			}
+			if got := om.held("AAPL"); got != tt.wantHeld {
+				t.Errorf("OrderManager.Sell() held = %v, want %v", got, tt.wantHeld)
+			}
+			if om.port.cash != tt.wantCash {
+				t.Errorf("OrderManager.Sell() cash = %v, want %v", om.port.cash, tt.wantCash)
+			}
+		})
+	}
+}
+
+func TestOrderManager_Buy_cover(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		volume   instruments.Volume
+		wantHeld instruments.Volume
+		wantPnL  instruments.Amount
+	}{
+		{"partial cover", 4, -6, 2000},
+		{"cover", 10, 0, 5000},
+		{"cover and buy", 15, 5, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			om.borrow = &Borrow{}
+			om.Add(NewOrder("AAPL", false, instruments.Market, instruments.NewPrice(20), 10, ts))
+			om.Add(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(15), tt.volume, ts))
+
+			if got := om.held("AAPL"); got != tt.wantHeld {
+				t.Errorf("OrderManager.Buy() held = %v, want %v", got, tt.wantHeld)
+			}
+			var pnl instruments.Amount
+			for _, lot := range om.log.Lots() {
+				pnl += lot.RealizedPnL
+			}
+			if pnl != tt.wantPnL {
+				t.Errorf("OrderManager.Buy() realized PnL = %v, want %v", pnl, tt.wantPnL)
+			}
+		})
+	}
+}
+
+func TestOrderManager_Accrue(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+
+	om := mockOrderManager(4000)
+	om.borrow = &Borrow{Rate: 0.36}
+	om.Accrue(ts)
+	om.Add(NewOrder("AAPL", false, instruments.Market, instruments.NewPrice(36), 100, ts))
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		wantCash instruments.Amount
+	}{
+		{"same day", ts.Add(time.Hour), 760000},
+		{"two days later", ts.AddDate(0, 0, 2), 759280},
+		{"earlier", ts, 759280},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om.Accrue(tt.at)
+			if om.port.cash != tt.wantCash {
+				t.Errorf("OrderManager.Accrue() cash = %v, want %v", om.port.cash, tt.wantCash)
+			}
+		})
+	}
+}
+
+func TestPortfolio_CloseAll_short(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+
+	om := mockOrderManager(1000)
+	om.borrow = &Borrow{}
+	om.Add(NewOrder("AAPL", false, instruments.Market, instruments.NewPrice(20), 10, ts))
+	om.Add(NewOrder("MSFT", true, instruments.Market, instruments.NewPrice(10), 10, ts))
+	om.port.Update(mockQuote("AAPL", 17, 18), om)
+	om.port.Update(mockQuote("MSFT", 11, 12), om)
+
+	if err := om.port.CloseAll(om); err != nil {
+		t.Fatalf("Portfolio.CloseAll() error = %v", err)
+	}
+	for _, name := range []string{"AAPL", "MSFT"} {
+		if got := om.held(name); got != 0 {
+			t.Errorf("Portfolio.CloseAll() %v held = %v, want 0", name, got)
+		}
+	}
+	if want := instruments.Amount(100000 + 2000 + 1000); om.port.cash != want {
+		t.Errorf("Portfolio.CloseAll() cash = %v, want %v", om.port.cash, want)
+	}
+}
+
+func TestPortfolio_CloseAll_losingShort(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+
+	om := mockOrderManager(1000)
+	om.borrow = &Borrow{}
+	om.Add(NewOrder("AAPL", false, instruments.Market, instruments.NewPrice(10), 100, ts))
+	om.port.Update(mockQuote("AAPL", 39.95, 40), om)
+
+	if err := om.port.CloseAll(om); err != nil {
+		t.Fatalf("Portfolio.CloseAll() error = %v", err)
+	}
+	if got := om.held("AAPL"); got != 0 {
+		t.Errorf("Portfolio.CloseAll() held = %v, want 0", got)
+	}
+	if want := instruments.Amount(100000 + 100000 - 400000); om.port.cash != want {
+		t.Errorf("Portfolio.CloseAll() cash = %v, want %v", om.port.cash, want)
+	}
+}
@@ -24,122 +24,346 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 
-	"github.com/jakeschurch/goat/internal/output"
-
+	"github.com/jakeschurch/goat/internal/bars"
 	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/goat/internal/output"
 	"github.com/jakeschurch/goat/internal/worker"
 	"github.com/jakeschurch/instruments"
 )
 
-var (
-	orderManager   *OrderManager
-	Port           *Portfolio
-	performanceLog *output.PerformanceLog
-	// benchmark      *Benchmark
-)
-
-func init() {
-	orderManager = NewOrderManager()
-	Port = NewPortfolio(instruments.Amount(0))
-	performanceLog = output.NewPerformanceLog()
-}
-
 // ReadConfig
 func ReadConfig(filename string) config.Config {
 	return config.ReadConfig(filename)
 }
 
+// MissingColumnsError is returned by Run when columns named by their
+// header text are not found in a quote file's header row.
+type MissingColumnsError = worker.MissingColumnsError
+
+// Sink is where the results of a simulation are written to.
+// Results are named "holdings", "equity", "lots", "audit" and "stats";
+// Open is called once for each, then Close once they have all been written.
+type Sink = output.Sink
+
+// FileSink returns a Sink that writes holding results to the file at path,
+// and each other set of results to a file beside it named after them.
+func FileSink(path string) Sink {
+	return output.FileSink(path)
+}
+
+// WriterSink returns a Sink that writes every set of results to w as a single
+// document: a JSON object keyed by result name, or CSV tables separated by a blank line.
+func WriterSink(w io.Writer) Sink {
+	return &output.WriterSink{Writer: w}
+}
+
+// newSink returns a FileSink for path, or a WriterSink
+// for stdout if path is empty or "-".
+func newSink(path string) Sink {
+	if path == "" || path == "-" {
+		return WriterSink(os.Stdout)
+	}
+	return FileSink(path)
+}
+
 // Algorithm is an interface that needs to be implemented in the pipeline by a user to fill orders based on the conditions that they specify.
+// Buy may return an order made with NewBracketOrder, to have take profit and
+// stop orders placed once it fills, or with OneCancelsOther to place linked orders.
+// Algorithms that also implement ExecutionListener are told how their orders are executed.
+// If short selling is allowed, Buy may return a sell order to sell short,
+// and Sell is called with short holdings, which have a negative volume,
+// to return buy orders that cover them.
 type Algorithm interface {
-	Buy(instruments.Quote) (*instruments.Order, bool)
-	Sell(instruments.Quote, *instruments.Holding) (*instruments.Order, bool)
+	Buy(instruments.Quote) (*Order, bool)
+	Sell(instruments.Quote, *instruments.Holding) (*Order, bool)
 }
+
+// Simulation is a single backtest. Each Simulation owns its portfolio,
+// order manager and performance log, so that many may be run concurrently.
 type Simulation struct {
 	conf   config.Config
 	algos  []Algorithm
 	ignore sync.Map
+
+	port    *Portfolio
+	orders  *OrderManager
+	perfLog *output.PerformanceLog
+	sink    Sink
+
+	// bench is marked to market alongside port, from quotes of its
+	// security or from benchPrices if a benchmark file is used.
+	bench       *Benchmark
+	benchPrices []instruments.Quote
+
+	// barBuilder builds bars for bar algorithms and bar warm-ups;
+	// it is nil if there are neither.
+	barBuilder *bars.Builder
+	warm       warmUp
+
+	// quoteCount, lastQuote and lastSample track when equity was last sampled.
+	quoteCount            int
+	lastQuote, lastSample time.Time
+
+	// err is an error in the config, returned when the simulation is run.
+	err error
 }
 
 func NewSim(c config.Config, algos ...Algorithm) *Simulation {
+	var cash = instruments.NewAmount(instruments.NewPrice(1.00), instruments.NewVolume(c.Backtest.StartCashAmt))
+	var port = NewPortfolio(cash)
+	var perfLog = output.NewPerformanceLog()
+	perfLog.SetRiskFreeRate(c.Simulation.RiskFreeRate)
+
 	var sim = &Simulation{
-		conf:   c,
-		algos:  algos,
-		ignore: sync.Map{},
+		conf:    c,
+		algos:   make([]Algorithm, len(algos)),
+		ignore:  sync.Map{},
+		port:    port,
+		orders:  NewOrderManager(port, perfLog),
+		perfLog: perfLog,
+		sink:    newSink(c.Simulation.OutputPath),
+		warm:    newWarmUp(c),
+	}
+	for i, algo := range algos {
+		sim.algos[i] = algo
+		if ha, ok := algo.(HandledAlgorithm); ok {
+			sim.algos[i] = handled{algo, ha, sim.orders.NewHandle()}
+		}
+		if _, ok := algo.(BarAlgorithm); ok && sim.barBuilder == nil {
+			sim.barBuilder = newBarBuilder(c)
+		}
+	}
+	if c.Simulation.WarmUpBars > 0 && sim.barBuilder == nil {
+		sim.barBuilder = newBarBuilder(c)
 	}
 	for _, name := range c.Backtest.IgnoreSecurities {
 		sim.ignore.Store(name, struct{}{})
 	}
-	Port.cash += instruments.NewAmount(instruments.NewPrice(1.00), instruments.NewVolume(c.Backtest.StartCashAmt))
+	if c.Benchmark.Use && c.Benchmark.Symbol != "" {
+		sim.bench = NewBenchmark(c.Benchmark.Symbol, cash)
+	}
+	sim.orders.costMethod, sim.err = ParseCostMethod(c.Simulation.CostMethod)
+	sim.orders.borrow = NewBorrow(c)
+	sim.orders.margin = NewMargin(c)
+	sim.orders.close = sessionClose(c)
+	sim.orders.slippage = NewSlippageModel(c)
+	sim.orders.commission = NewCommissionModel(c)
 	return sim
 }
 
+// Portfolio returns the simulation's portfolio.
+func (sim *Simulation) Portfolio() *Portfolio {
+	return sim.port
+}
+
+// SetSink replaces the sink results are written to when a simulation ends.
+func (sim *Simulation) SetSink(sink Sink) {
+	sim.sink = sink
+}
+
+// SetSlippage replaces the slippage model picked from the simulation's config.
+func (sim *Simulation) SetSlippage(model SlippageModel) {
+	sim.orders.slippage = model
+}
+
+// SetCommission replaces the commission model picked from the simulation's config.
+func (sim *Simulation) SetCommission(model CommissionModel) {
+	sim.orders.commission = model
+}
+
 // checkBuys from quote information.
 // Buy Orders handled by Simulation;
 // sells by Portfolios.
-func (sim *Simulation) checkBuys(quote instruments.Quote) *instruments.Order {
+// The order is returned along with the algorithm that created it.
+func (sim *Simulation) checkBuys(quote instruments.Quote) (*Order, Algorithm) {
 	for _, algo := range sim.algos {
 		if order, ok := algo.Buy(quote); ok {
-			return order
+			return order, algo
 		}
 	}
-	return nil
+	return nil, nil
 }
 
+// Run the simulation over every file matched by the configured file glob,
+// in date order. Holdings are carried over from one file to the next,
+// and are only closed out once the last file has been read.
 func (sim *Simulation) Run() error {
-	var file io.ReadSeeker
-
-	// Setup file reader
-	var fname, date, err = sim.conf.FileInfo()
+	if sim.err != nil {
+		return sim.err
+	}
+	var files, err = sim.conf.Files()
 	if err != nil {
 		return err
 	}
-	// Setup Worker & WorkerConfig
-	wc := worker.Config{
-		Name: sim.conf.File.Columns.Ticker,
-		Bid:  sim.conf.File.Columns.Bid, BidSz: sim.conf.File.Columns.BidSize,
-		Ask: sim.conf.File.Columns.Ask, AskSz: sim.conf.File.Columns.AskSize,
-		Timestamp: sim.conf.File.Columns.Timestamp, Date: date,
-		Timeunit: sim.conf.File.TimestampUnit,
-	}
-	worker := worker.New(wc)
-
-	quoteChan := make(chan *instruments.Quote)
-	done := make(chan struct{})
-
-	go func(inChan <-chan *instruments.Quote) {
-		var quote *instruments.Quote
-		var ok bool
-	loop:
-		for {
-			if quote, ok = <-quoteChan; !ok {
-				break loop
-			}
-			if quote != nil {
-				if _, ok := sim.ignore.Load(quote.Name); !ok {
-					sim.process(quote)
-				}
-			}
-			continue
+	if err = sim.readBenchmark(); err != nil {
+		return err
+	}
+	for _, f := range files {
+		src, err := NewFileSource(sim.conf, f)
+		if err != nil {
+			return err
+		}
+		if err = sim.runSource(src); err != nil {
+			return err
+		}
+	}
+	return sim.finish()
+}
+
+// RunSources runs the simulation over quotes from each source in turn,
+// rather than from the configured files. Holdings are carried over from
+// one source to the next, and are only closed out once the last source has been read.
+func (sim *Simulation) RunSources(sources ...QuoteSource) error {
+	if sim.err != nil {
+		return sim.err
+	}
+	if err := sim.readBenchmark(); err != nil {
+		return err
+	}
+	for _, src := range sources {
+		if err := sim.runSource(src); err != nil {
+			return err
 		}
-		close(done)
-	}(quoteChan)
+	}
+	return sim.finish()
+}
 
-	if file, err = os.Open(fname); err != nil {
+// readBenchmark reads benchmark prices if a benchmark file is used.
+func (sim *Simulation) readBenchmark() (err error) {
+	if sim.bench != nil && sim.conf.Benchmark.File != "" {
+		sim.benchPrices, err = readBenchmarkPrices(sim.bench.Name, sim.conf.Benchmark.File)
+	}
+	return err
+}
+
+// finish closes out open orders and holdings, and writes out results.
+func (sim *Simulation) finish() error {
+	sim.orders.CancelAll()
+	if err := sim.port.CloseAll(sim.orders); err != nil {
 		return err
 	}
-	go worker.Run(quoteChan, file)
-	<-done
+	// Equity once holdings are closed out takes the place
+	// of any sample already taken at the last quote.
+	if sim.warm.done {
+		sim.perfLog.SetEquity(sim.equity(sim.lastQuote))
+	}
+	return sim.perfLog.OutputResults(output.ParseFormat(sim.conf.Simulation.OutputFormat), sim.sink)
+}
+
+// runSource streams quotes from a single source through the simulation,
+// closing the source once it has been read.
+func (sim *Simulation) runSource(src QuoteSource) error {
+	defer src.Close()
+	for {
+		quote, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		_, ignored := sim.ignore.Load(quote.Name)
+		sim.updateBenchmark(quote, ignored)
+		if !ignored {
+			sim.process(quote)
+		}
+	}
 
-	Port.CloseAll()
-	performanceLog.OutputResults(output.CSV, "/home/jake/Desktop/simResults.csv")
+	// Close out the source's bars, and sample equity at the end of each
+	// source once warmed up, unless it was sampled at the last quote.
+	if sim.barBuilder != nil {
+		sim.onBars(sim.barBuilder.Flush())
+	}
+	if sim.warm.done && sim.lastQuote.After(sim.lastSample) {
+		sim.lastSample = sim.lastQuote
+		sim.perfLog.AddEquity(sim.equity(sim.lastQuote))
+	}
 	return nil
 }
 
+// updateBenchmark marks the benchmark to market as of a quote.
+// If benchmark prices are read from a file, every price
+// up until the quote's timestamp is used.
+// The benchmark is not bought until the warm-up period has passed;
+// ignored quotes do not start or end the warm-up period.
+func (sim *Simulation) updateBenchmark(quote *instruments.Quote, ignored bool) {
+	if sim.bench == nil {
+		return
+	}
+	var warming = !sim.warm.done
+	if !ignored {
+		warming = sim.warm.warming(quote.Timestamp)
+	}
+	if sim.conf.Benchmark.File == "" {
+		if !warming {
+			sim.bench.Update(*quote)
+		}
+		return
+	}
+	for len(sim.benchPrices) > 0 && !sim.benchPrices[0].Timestamp.After(quote.Timestamp) {
+		if !warming {
+			sim.bench.Update(sim.benchPrices[0])
+		}
+		sim.benchPrices = sim.benchPrices[1:]
+	}
+}
+
+// equity marks the portfolio and benchmark to market.
+func (sim *Simulation) equity(timestamp time.Time) output.Equity {
+	var e = sim.port.Equity(timestamp)
+	if sim.bench != nil {
+		e.Benchmark = sim.bench.Value()
+	}
+	return e
+}
+
+// sampleEquity records the portfolio's equity every EquityQuotes quotes,
+// or otherwise every BarRate. If neither is set, equity is only
+// sampled at the end of each file.
+func (sim *Simulation) sampleEquity(timestamp time.Time) {
+	var every, rate = sim.conf.Simulation.EquityQuotes, sim.conf.Simulation.BarRate
+
+	sim.quoteCount++
+	sim.lastQuote = timestamp
+
+	switch {
+	case every > 0:
+		if sim.quoteCount%every != 0 {
+			return
+		}
+	case rate > 0:
+		if timestamp.Before(sim.lastSample.Add(rate)) {
+			return
+		}
+	default:
+		return
+	}
+	sim.lastSample = timestamp
+	sim.perfLog.AddEquity(sim.equity(timestamp))
+}
+
 func (sim *Simulation) process(quote *instruments.Quote) {
+	if sim.warm.warming(quote.Timestamp) {
+		sim.prime(*quote)
+		return
+	}
+	// Charge fees and interest for any days passed, then
+	// fill any resting orders that the quote crosses.
+	sim.orders.Accrue(quote.Timestamp)
+	sim.orders.Match(*quote)
+
 	// Check if we can buy new holding
-	if newBuy := sim.checkBuys(*quote); newBuy != nil {
-		orderManager.Add(newBuy)
+	if newBuy, algo := sim.checkBuys(*quote); newBuy != nil {
+		sim.orders.Listen(newBuy, algo)
+		sim.orders.Add(newBuy)
+	}
+	if sim.barBuilder != nil {
+		sim.onBars(sim.barBuilder.Update(*quote))
 	}
-	Port.Update(*quote, sim.algos...)
+	sim.port.Update(*quote, sim.orders, sim.algos...)
+	// Liquidate holdings if equity has fallen below maintenance margin.
+	sim.orders.MarginCall(quote.Timestamp)
+	sim.sampleEquity(quote.Timestamp)
 }
@@ -21,10 +21,12 @@
 package goat
 
 import (
+	"bytes"
 	"path/filepath"
 	"reflect"
-	"sync"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jakeschurch/instruments"
 
@@ -57,14 +59,14 @@ func TestReadConfig(t *testing.T) {
 
 type Algorithm_Example struct{}
 
-func (algo Algorithm_Example) Buy(quote instruments.Quote) (*instruments.Order, bool) {
-	newOrder := quote.FillOrder(quote.Ask.Price, instruments.NewVolume(20.00), true, instruments.Market)
+func (algo Algorithm_Example) Buy(quote instruments.Quote) (*Order, bool) {
+	newOrder := FillOrder(quote, quote.Ask.Price, instruments.NewVolume(20.00), true, instruments.Market)
 	return newOrder, true
 }
 
-func (algo Algorithm_Example) Sell(quote instruments.Quote, holding *instruments.Holding) (*instruments.Order, bool) {
+func (algo Algorithm_Example) Sell(quote instruments.Quote, holding *instruments.Holding) (*Order, bool) {
 	if quote.Name == holding.Name && quote.Bid.Price > holding.Buy.Price {
-		newOrder := quote.FillOrder(quote.Bid.Price, holding.Volume, false, instruments.Market)
+		newOrder := FillOrder(quote, quote.Bid.Price, holding.Volume, false, instruments.Market)
 		return newOrder, true
 	}
 	return nil, false
@@ -73,18 +75,22 @@ func (algo Algorithm_Example) Sell(quote instruments.Quote, holding *instruments
 
 func TestNewSim(t *testing.T) {
 	// SETUP
-	filename, err := filepath.Abs("../../example/config.json")
+	filename, err := filepath.Abs("example/config.json")
 	if err != nil {
 		panic("could not read json")
 	}
 
-	wanted := &Simulation{
-		conf:   ReadConfig(filename),
-		algos:  []Algorithm{Algorithm_Example{}},
-		ignore: sync.Map{},
+	conf := ReadConfig(filename)
+	if conf.Backtest.StartCashAmt == 0 {
+		t.Fatalf("ReadConfig(%v) did not load the example config", filename)
 	}
-	for _, name := range wanted.conf.Backtest.IgnoreSecurities {
-		wanted.ignore.Store(name, struct{}{})
+	port := NewPortfolio(instruments.NewAmount(instruments.NewPrice(1.00), instruments.NewVolume(conf.Backtest.StartCashAmt)))
+
+	wanted := &Simulation{
+		conf:  conf,
+		algos: []Algorithm{Algorithm_Example{}},
+		port:  port,
+		sink:  FileSink(conf.Simulation.OutputPath),
 	}
 	// END SETUP
 
@@ -101,9 +107,143 @@ func TestNewSim(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewSim(tt.args.c, tt.args.algos...); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewSim() = %v, want %v", got, tt.want)
+			got := NewSim(tt.args.c, tt.args.algos...)
+			if !reflect.DeepEqual(got.conf, tt.want.conf) || !reflect.DeepEqual(got.algos, tt.want.algos) {
+				t.Errorf("NewSim() = %v, %v, want %v, %v", got.conf, got.algos, tt.want.conf, tt.want.algos)
+			}
+			if !reflect.DeepEqual(got.port, tt.want.port) || !reflect.DeepEqual(got.sink, tt.want.sink) {
+				t.Errorf("NewSim() portfolio, sink = %v, %v, want %v, %v", got.port, got.sink, tt.want.port, tt.want.sink)
+			}
+			for _, name := range tt.args.c.Backtest.IgnoreSecurities {
+				if _, ok := got.ignore.Load(name); !ok {
+					t.Errorf("NewSim() does not ignore %v", name)
+				}
+			}
+			if got.orders == nil || got.orders.port != got.port || got.orders.log != got.perfLog {
+				t.Errorf("NewSim() order manager is not scoped to the simulation")
 			}
 		})
 	}
 }
+
+func TestNewSim_isolated(t *testing.T) {
+	filename, err := filepath.Abs("example/config.json")
+	if err != nil {
+		panic("could not read json")
+	}
+	conf := ReadConfig(filename)
+	if conf.Backtest.StartCashAmt == 0 {
+		t.Fatalf("ReadConfig(%v) did not load the example config", filename)
+	}
+	conf.Backtest.StartCashAmt = 1000
+
+	first, second := NewSim(conf, Algorithm_Example{}), NewSim(conf, Algorithm_Example{})
+	if first.port == second.port || first.orders == second.orders || first.perfLog == second.perfLog {
+		t.Fatalf("NewSim() simulations share state")
+	}
+	want := instruments.NewAmount(instruments.NewPrice(1.00), instruments.NewVolume(1000))
+	if got := second.Portfolio().cash; got != want {
+		t.Errorf("NewSim() second cash = %v, want %v", got, want)
+	}
+}
+
+func TestSimulation_sampleEquity(t *testing.T) {
+	var start = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		equityQuotes int
+		barRate      time.Duration
+		want         int
+	}{
+		{"every quote", 1, 0, 10},
+		{"every 4 quotes", 4, 0, 2},
+		{"every 3 seconds", 0, 3 * time.Second, 4},
+		{"end of file only", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conf config.Config
+			conf.Simulation.EquityQuotes, conf.Simulation.BarRate = tt.equityQuotes, tt.barRate
+			sim := NewSim(conf)
+			for i := 0; i < 10; i++ {
+				sim.sampleEquity(start.Add(time.Duration(i) * time.Second))
+			}
+			if got := len(sim.perfLog.EquityCurve()); got != tt.want {
+				t.Errorf("Simulation.sampleEquity() samples = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortfolio_Equity(t *testing.T) {
+	om := mockOrderManager(1000)
+	om.Add(NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10.00), 10, time.Now()))
+	om.port.Update(mockQuote("AAPL", 12.00, 12.05), om)
+
+	got := om.port.Equity(time.Now())
+	if want := instruments.NewAmount(instruments.NewPrice(120.00), 1); got.MarketValue != want || got.GrossExposure != want {
+		t.Errorf("Portfolio.Equity() market value = %v, want %v", got.MarketValue, want)
+	}
+	if want := instruments.NewAmount(instruments.NewPrice(1020.00), 1); got.Equity != want {
+		t.Errorf("Portfolio.Equity() equity = %v, want %v", got.Equity, want)
+	}
+}
+
+func TestSimulation_Run(t *testing.T) {
+	conf := ReadConfig("example/config.json")
+	conf.File.Glob = "example/testQuotes_*"
+
+	tests := []struct {
+		name      string
+		format    string
+		wantFirst string
+	}{
+		{"csv", "csv", "Name,"},
+		{"json", "json", `{"holdings":[[`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			conf.Simulation.OutputFormat = tt.format
+			sim := NewSim(conf, Algorithm_Example{})
+			sim.SetSink(WriterSink(&buf))
+			if err := sim.Run(); err != nil {
+				t.Fatalf("Simulation.Run() error = %v", err)
+			}
+			if got := buf.String(); !strings.HasPrefix(got, tt.wantFirst) || !strings.Contains(strings.ToLower(got), "sharpe") {
+				t.Errorf("Simulation.Run() output = %v", got)
+			}
+		})
+	}
+}
+
+func TestSimulation_Run_noFiles(t *testing.T) {
+	var conf config.Config
+	conf.File.Glob = "example/noSuchQuotes_*"
+
+	if err := NewSim(conf).Run(); err != config.ErrNoFiles {
+		t.Errorf("Simulation.Run() error = %v, want %v", err, config.ErrNoFiles)
+	}
+}
+
+func TestSimulation_Run_costMethod(t *testing.T) {
+	conf := ReadConfig("example/config.json")
+	conf.File.Glob = "example/testQuotes_*"
+	conf.Simulation.CostMethod = "newest"
+
+	if err := NewSim(conf, Algorithm_Example{}).Run(); err != ErrCostMethod {
+		t.Errorf("Simulation.Run() error = %v, want %v", err, ErrCostMethod)
+	}
+}
+
+func TestSimulation_Run_namedColumns(t *testing.T) {
+	conf := ReadConfig("example/config.json")
+	conf.File.Glob = "example/testQuotes_*"
+	conf.File.Columns.Bid.Name, conf.File.Columns.Ask.Name = "Best_Bid_Price", "Best_Ask_Price"
+
+	err := NewSim(conf, Algorithm_Example{}).Run()
+	if missing, ok := err.(*MissingColumnsError); !ok || !reflect.DeepEqual(missing.Missing, []string{"Best_Ask_Price"}) {
+		t.Errorf("Simulation.Run() error = %v, want Best_Ask_Price missing", err)
+	}
+}
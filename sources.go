@@ -0,0 +1,301 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/goat/internal/worker"
+	"github.com/jakeschurch/instruments"
+)
+
+// ErrBadFormat is returned by a source whose data is not in its format.
+var ErrBadFormat = errors.New("quotes are not in the source's format")
+
+// QuoteSource yields quotes in timestamp order.
+// Sources may be passed to Simulation.RunSources to read quotes from
+// anywhere other than the configured files.
+type QuoteSource interface {
+	// Next returns the next quote, or io.EOF once every quote has been read.
+	Next() (*instruments.Quote, error)
+	// Close releases the source; it is called once a simulation is done with it.
+	Close() error
+}
+
+// NewFileSource opens a quote file as a source in the configured File.Format:
+// "jsonl" for JSON Lines, "binary" for goat's binary format, or delimited text otherwise.
+// Files compressed with gzip, zstd or bzip2 are decompressed as they are read.
+func NewFileSource(c config.Config, f config.DataFile) (QuoteSource, error) {
+	file, err := openQuotes(f.Name)
+	if err != nil {
+		return nil, err
+	}
+	switch c.File.Format {
+	case "jsonl":
+		return &closingSource{NewJSONSource(file), file}, nil
+	case "binary":
+		return &closingSource{NewBinarySource(file), file}, nil
+	default:
+		return NewDelimitedSource(c, f.Date, file), nil
+	}
+}
+
+// closingSource closes the file a source reads from along with the source.
+type closingSource struct {
+	QuoteSource
+	file io.Closer
+}
+
+func (s *closingSource) Close() error {
+	s.QuoteSource.Close()
+	return s.file.Close()
+}
+
+// ----------------------------------------------------------------------------
+
+// DelimitedSource reads quotes from delimited text, such as TAQ files,
+// with the columns and delimiters set in a config. Timestamps are
+// read as offsets from date in File.TimestampUnit.
+type DelimitedSource struct {
+	worker *worker.Worker
+	quotes chan *instruments.Quote
+	r      io.ReadCloser
+}
+
+// NewDelimitedSource starts reading quotes from r, which is closed with the source.
+func NewDelimitedSource(c config.Config, date time.Time, r io.ReadCloser) *DelimitedSource {
+	var cols = c.File.Columns
+	wc := worker.Config{
+		Name: cols.Ticker.Index,
+		Bid:  cols.Bid.Index, BidSz: cols.BidSize.Index,
+		Ask: cols.Ask.Index, AskSz: cols.AskSize.Index,
+		Timestamp: cols.Timestamp.Index, Date: date,
+		Timeunit: c.File.TimestampUnit,
+		Parsers:  c.File.Parsers,
+		Delim:    c.File.Delim, Quoted: c.File.Quoted,
+		Headers: c.File.Headers, Comments: c.File.Comments,
+	}
+	wc.Headings.Name, wc.Headings.Timestamp = cols.Ticker.Name, cols.Timestamp.Name
+	wc.Headings.Bid, wc.Headings.BidSz = cols.Bid.Name, cols.BidSize.Name
+	wc.Headings.Ask, wc.Headings.AskSz = cols.Ask.Name, cols.AskSize.Name
+
+	var s = &DelimitedSource{worker: worker.New(wc), quotes: make(chan *instruments.Quote), r: r}
+	go s.worker.Run(s.quotes, r)
+	return s
+}
+
+func (s *DelimitedSource) Next() (*instruments.Quote, error) {
+	if quote, ok := <-s.quotes; ok {
+		return quote, nil
+	}
+	if err := s.worker.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Close stops reading, discarding any quotes that have already been read.
+func (s *DelimitedSource) Close() error {
+	var err = s.r.Close()
+	go func() {
+		for range s.quotes {
+		}
+	}()
+	return err
+}
+
+// ----------------------------------------------------------------------------
+
+// jsonQuote is a quote as a line of JSON.
+type jsonQuote struct {
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+	Bid       float64   `json:"bid"`
+	BidSize   float64   `json:"bidSize"`
+	Ask       float64   `json:"ask"`
+	AskSize   float64   `json:"askSize"`
+}
+
+// JSONSource reads quotes from JSON Lines: one object per line, such as
+// {"name":"AAPL","timestamp":"2017-08-14T09:30:00Z","bid":159.1,"bidSize":100,"ask":159.12,"askSize":200}.
+// Blank lines are skipped.
+type JSONSource struct {
+	dec *json.Decoder
+}
+
+func NewJSONSource(r io.Reader) *JSONSource {
+	return &JSONSource{dec: json.NewDecoder(r)}
+}
+
+func (s *JSONSource) Next() (*instruments.Quote, error) {
+	var q jsonQuote
+	if err := s.dec.Decode(&q); err != nil {
+		return nil, err
+	}
+	return &instruments.Quote{
+		Name: q.Name, Timestamp: q.Timestamp,
+		Bid: instruments.NewQuotedMetric(q.Bid, q.BidSize),
+		Ask: instruments.NewQuotedMetric(q.Ask, q.AskSize),
+	}, nil
+}
+
+func (s *JSONSource) Close() error {
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+
+// binaryMagic starts every file in goat's binary format, followed by a version.
+const binaryMagic, binaryVersion = "GOAT", 1
+
+// BinaryWriter writes quotes in goat's binary format: a "GOAT" header and
+// version byte, then each quote as a length-prefixed name followed by
+// varints of its Unix timestamp in nanoseconds, bid, bid size, ask and ask size.
+// Prices are kept in cents, so no precision is lost.
+type BinaryWriter struct {
+	w      *bufio.Writer
+	header bool
+	buf    [binary.MaxVarintLen64]byte
+}
+
+func NewBinaryWriter(w io.Writer) *BinaryWriter {
+	return &BinaryWriter{w: bufio.NewWriter(w)}
+}
+
+// Write a quote. Quotes are buffered until Flush is called.
+func (bw *BinaryWriter) Write(quote *instruments.Quote) error {
+	if !bw.header {
+		bw.w.WriteString(binaryMagic)
+		bw.w.WriteByte(binaryVersion)
+		bw.header = true
+	}
+	var bid, ask = quote.Bid, quote.Ask
+	if bid == nil {
+		bid = &instruments.QuotedMetric{}
+	}
+	if ask == nil {
+		ask = &instruments.QuotedMetric{}
+	}
+
+	bw.w.Write(bw.buf[:binary.PutUvarint(bw.buf[:], uint64(len(quote.Name)))])
+	bw.w.WriteString(quote.Name)
+	for _, v := range []int64{
+		quote.Timestamp.UnixNano(),
+		int64(bid.Price), int64(bid.Volume), int64(ask.Price), int64(ask.Volume),
+	} {
+		if _, err := bw.w.Write(bw.buf[:binary.PutVarint(bw.buf[:], v)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered quotes.
+func (bw *BinaryWriter) Flush() error {
+	return bw.w.Flush()
+}
+
+// BinarySource reads quotes written by a BinaryWriter.
+type BinarySource struct {
+	r      *bufio.Reader
+	header bool
+}
+
+func NewBinarySource(r io.Reader) *BinarySource {
+	return &BinarySource{r: bufio.NewReader(r)}
+}
+
+func (s *BinarySource) Next() (*instruments.Quote, error) {
+	if !s.header {
+		var header = make([]byte, len(binaryMagic)+1)
+		if _, err := io.ReadFull(s.r, header); err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			return nil, ErrBadFormat
+		}
+		if string(header[:len(binaryMagic)]) != binaryMagic || header[len(binaryMagic)] != binaryVersion {
+			return nil, ErrBadFormat
+		}
+		s.header = true
+	}
+
+	length, err := binary.ReadUvarint(s.r)
+	if err != nil {
+		// A file may only end between quotes.
+		return nil, err
+	}
+	var name = make([]byte, length)
+	if _, err = io.ReadFull(s.r, name); err != nil {
+		return nil, ErrBadFormat
+	}
+	var v [5]int64
+	for i := range v {
+		if v[i], err = binary.ReadVarint(s.r); err != nil {
+			return nil, ErrBadFormat
+		}
+	}
+	return &instruments.Quote{
+		Name: string(name), Timestamp: time.Unix(0, v[0]).UTC(),
+		Bid: &instruments.QuotedMetric{Price: instruments.Price(v[1]), Volume: instruments.Volume(v[2])},
+		Ask: &instruments.QuotedMetric{Price: instruments.Price(v[3]), Volume: instruments.Volume(v[4])},
+	}, nil
+}
+
+func (s *BinarySource) Close() error {
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+
+// SliceSource yields quotes held in memory, sorted by timestamp.
+// It is useful for tests.
+type SliceSource struct {
+	quotes []*instruments.Quote
+}
+
+func NewSliceSource(quotes ...*instruments.Quote) *SliceSource {
+	var s = &SliceSource{quotes: append([]*instruments.Quote(nil), quotes...)}
+	sort.SliceStable(s.quotes, func(i, j int) bool {
+		return s.quotes[i].Timestamp.Before(s.quotes[j].Timestamp)
+	})
+	return s
+}
+
+func (s *SliceSource) Next() (*instruments.Quote, error) {
+	if len(s.quotes) == 0 {
+		return nil, io.EOF
+	}
+	var quote = s.quotes[0]
+	s.quotes = s.quotes[1:]
+	return quote, nil
+}
+
+func (s *SliceSource) Close() error {
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/instruments"
+)
+
+// readAll returns every quote from a source, and the error that ended it.
+func readAll(src QuoteSource) ([]*instruments.Quote, error) {
+	var quotes = make([]*instruments.Quote, 0)
+	for {
+		quote, err := src.Next()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return quotes, err
+		}
+		quotes = append(quotes, quote)
+	}
+}
+
+func sourceQuotes() []*instruments.Quote {
+	var first, second = mockQuote("AAPL", 159.1, 159.12), mockQuote("MSFT", 72.5, 72.51)
+	second.Timestamp = second.Timestamp.Add(time.Second)
+	return []*instruments.Quote{&first, &second}
+}
+
+func TestSliceSource(t *testing.T) {
+	var quotes = sourceQuotes()
+	got, err := readAll(NewSliceSource(quotes[1], quotes[0]))
+	if err != nil || !reflect.DeepEqual(got, quotes) {
+		t.Errorf("SliceSource.Next() = %v, %v, want %v in timestamp order", got, err, quotes)
+	}
+}
+
+func TestJSONSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   string
+		want    []*instruments.Quote
+		wantErr bool
+	}{
+		{"lines", `{"name":"AAPL","timestamp":"2017-08-14T09:30:00Z","bid":159.1,"bidSize":100,"ask":159.12,"askSize":100}
+
+{"name":"MSFT","timestamp":"2017-08-14T09:30:01Z","bid":72.5,"bidSize":100,"ask":72.51,"askSize":100}
+`, sourceQuotes(), false},
+		{"malformed", `{"name":"AAPL",`, []*instruments.Quote{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(NewJSONSource(strings.NewReader(tt.lines)))
+			if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSONSource.Next() = %v, %v, want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBinarySource(t *testing.T) {
+	var buf bytes.Buffer
+	var w = NewBinaryWriter(&buf)
+	for _, quote := range sourceQuotes() {
+		if err := w.Write(quote); err != nil {
+			t.Fatalf("BinaryWriter.Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("BinaryWriter.Flush() error = %v", err)
+	}
+	var file = buf.Bytes()
+
+	tests := []struct {
+		name    string
+		file    []byte
+		want    []*instruments.Quote
+		wantErr error
+	}{
+		{"round trip", file, sourceQuotes(), nil},
+		{"empty", nil, []*instruments.Quote{}, nil},
+		{"not binary", []byte("Time|Symbol|Bid\n"), []*instruments.Quote{}, ErrBadFormat},
+		{"truncated", file[:len(file)-2], sourceQuotes()[:1], ErrBadFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(NewBinarySource(bytes.NewReader(tt.file)))
+			if err != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BinarySource.Next() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewFileSource(t *testing.T) {
+	conf := ReadConfig("example/config.json")
+	conf.File.Glob = "example/testQuotes_*"
+	files, err := conf.Files()
+	if err != nil {
+		t.Fatalf("Config.Files() error = %v", err)
+	}
+
+	src, err := NewFileSource(conf, files[0])
+	if err != nil {
+		t.Fatalf("NewFileSource() error = %v", err)
+	}
+	quote, err := src.Next()
+	if err != nil || !quote.Timestamp.After(files[0].Date) {
+		t.Errorf("DelimitedSource.Next() = %v, %v, want a quote dated after %v", quote, err, files[0].Date)
+	}
+	// Closing a source before it has been read must not block.
+	if err = src.Close(); err != nil {
+		t.Errorf("DelimitedSource.Close() error = %v", err)
+	}
+
+	conf.File.Format = "jsonl"
+	if src, err = NewFileSource(conf, config.DataFile{Name: "example/noSuchQuotes_20170814"}); err == nil {
+		t.Errorf("NewFileSource() opened a missing file")
+	}
+}
+
+func TestSimulation_Run_jsonl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var lines = []string{
+		`{"name":"IBM","timestamp":"2017-08-14T09:30:00Z","bid":159.1,"bidSize":100,"ask":159.12,"askSize":100}`,
+		`{"name":"IBM","timestamp":"2017-08-15T09:30:00Z","bid":160.1,"bidSize":100,"ask":160.12,"askSize":100}`,
+		`{"name":"MSFT","timestamp":"2017-08-14T09:30:00Z","bid":72.5,"bidSize":100,"ask":72.51,"askSize":100}`,
+	}
+	for i, name := range []string{"quotes_20170814.jsonl", "quotes_20170815.jsonl", "msft.jsonl"} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(lines[i]+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	conf := ReadConfig("example/config.json")
+	conf.File.Glob, conf.File.Format = filepath.Join(dir, "*.jsonl"), "jsonl"
+	conf.Simulation.StartDate, conf.Simulation.EndDate = "20170815", ""
+
+	sim := NewSim(conf, Algorithm_Example{})
+	sim.SetSink(WriterSink(&buf))
+	if err = sim.Run(); err != nil {
+		t.Fatalf("Simulation.Run() error = %v", err)
+	}
+	// The undated file is read before the dated ones.
+	if want := time.Date(2017, 8, 15, 9, 30, 0, 0, time.UTC); !sim.lastQuote.Equal(want) {
+		t.Errorf("Simulation.Run() last quote = %v, want %v", sim.lastQuote, want)
+	}
+	if !strings.Contains(buf.String(), "MSFT") {
+		t.Errorf("Simulation.Run() output = %v, want MSFT holdings", buf.String())
+	}
+}
+
+func TestSimulation_RunSources(t *testing.T) {
+	var buf bytes.Buffer
+	var conf config.Config
+	conf.Backtest.StartCashAmt = 100000
+
+	sim := NewSim(conf, Algorithm_Example{})
+	sim.SetSink(WriterSink(&buf))
+	var quotes = sourceQuotes()
+	if err := sim.RunSources(NewSliceSource(quotes[0]), NewSliceSource(quotes[1])); err != nil {
+		t.Fatalf("Simulation.RunSources() error = %v", err)
+	}
+	// Equity is sampled at the end of each source, and the last sample
+	// is replaced once holdings are closed out rather than repeated.
+	curve := sim.perfLog.EquityCurve()
+	if len(curve) != 2 || !curve[0].Timestamp.Before(curve[1].Timestamp) {
+		t.Errorf("Simulation.RunSources() equity samples = %v, want one at the end of each source", curve)
+	}
+	if !strings.Contains(buf.String(), "AAPL") {
+		t.Errorf("Simulation.RunSources() output = %v, want AAPL holdings", buf.String())
+	}
+}
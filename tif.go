@@ -0,0 +1,113 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"time"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+// defaultSessionClose is when sessions close if no close is configured.
+const defaultSessionClose = 16 * time.Hour
+
+// sessionClose returns the time of day that sessions close at, as set in a config.
+func sessionClose(c config.Config) time.Duration {
+	if c.Simulation.SessionClose == "" {
+		return defaultSessionClose
+	}
+	t, err := time.Parse("15:04", c.Simulation.SessionClose)
+	if err != nil {
+		return defaultSessionClose
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// expires returns when an order expires, or the zero time if it does not.
+// DAY orders expire at the close of the session they are placed in;
+// orders placed after the close expire at the close of the next session.
+func (o *OrderManager) expires(order *Order) time.Time {
+	switch order.TIF {
+	case GTD:
+		return order.Expires
+	case DAY:
+		var placed = order.Timestamp()
+		var y, m, d = placed.Date()
+		var close = time.Date(y, m, d, 0, 0, 0, 0, placed.Location()).Add(o.close)
+		if !placed.Before(close) {
+			close = close.AddDate(0, 0, 1)
+		}
+		return close
+	}
+	return time.Time{}
+}
+
+// schedule an order to be expired, if it has a lifetime.
+func (o *OrderManager) schedule(order *Order) {
+	var expires = o.expires(order)
+	if expires.IsZero() {
+		return
+	}
+	if o.expiry.IsZero() || expires.Before(o.expiry) {
+		o.expiry = expires
+	}
+}
+
+// expire resting orders whose lifetimes have passed by timestamp.
+// Expired orders are cancelled.
+func (o *OrderManager) expire(timestamp time.Time) {
+	if o.expiry.IsZero() || timestamp.Before(o.expiry) {
+		return
+	}
+	o.expiry = time.Time{}
+
+	for name := range o.resting {
+		var orders = make([]*Order, 0)
+		if buys, err := o.GetBuys(name); err == nil {
+			orders = append(orders, buys...)
+		}
+		if sells, err := o.GetSells(name); err == nil {
+			orders = append(orders, sells...)
+		}
+		for _, order := range orders {
+			var expires = o.expires(order)
+			switch {
+			case expires.IsZero():
+			case !timestamp.Before(expires):
+				o.cancel(order, output.Expire)
+			case o.expiry.IsZero() || expires.Before(o.expiry):
+				o.expiry = expires
+			}
+		}
+		o.prune(name)
+	}
+}
+
+// fillable reports whether an order can be filled in full against quote.
+func fillable(order *Order, quote *instruments.Quote) bool {
+	if !triggered(order, quote) {
+		return false
+	}
+	var _, size = crossed(order, quote)
+	return size != nil && *size >= order.Remaining()
+}
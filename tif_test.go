@@ -0,0 +1,162 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/goat/internal/output"
+	"github.com/jakeschurch/instruments"
+)
+
+func TestOrderManager_Add_timeInForce(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	var limit = func(price float64, volume instruments.Volume, tif TimeInForce) *Order {
+		return NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(price), volume, ts).WithTIF(tif)
+	}
+	tests := []struct {
+		name       string
+		order      *Order
+		wantStatus instruments.Status
+		wantFilled instruments.Volume
+	}{
+		{"GTC rests", limit(9, 50, GTC), instruments.Open, 0},
+		{"IOC partially filled", limit(10, 50, IOC), instruments.Cancelled, 30},
+		{"IOC not crossed", limit(9, 50, IOC), instruments.Cancelled, 0},
+		{"FOK filled", limit(10, 20, FOK), instruments.Closed, 20},
+		{"FOK too large", limit(10, 50, FOK), instruments.Cancelled, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			om.Match(instruments.Quote{
+				Name: "AAPL", Timestamp: ts,
+				Bid: instruments.NewQuotedMetric(9.95, 100), Ask: instruments.NewQuotedMetric(10.00, 30),
+			})
+			om.Add(tt.order)
+
+			if tt.order.Status != tt.wantStatus {
+				t.Errorf("OrderManager.Add() status = %v, want %v", tt.order.Status, tt.wantStatus)
+			}
+			if tt.order.Filled() != tt.wantFilled {
+				t.Errorf("OrderManager.Add() filled = %v, want %v", tt.order.Filled(), tt.wantFilled)
+			}
+		})
+	}
+}
+
+func TestOrderManager_Add_timeInForceUnquoted(t *testing.T) {
+	var ts = time.Date(2017, 8, 14, 9, 30, 0, 0, time.UTC)
+	var market = func(tif TimeInForce) *Order {
+		return NewOrder("AAPL", true, instruments.Market, instruments.NewPrice(10), 20, ts).WithTIF(tif)
+	}
+	tests := []struct {
+		name       string
+		order      *Order
+		wantStatus instruments.Status
+		wantFilled instruments.Volume
+	}{
+		{"GTC filled at order price", market(GTC), instruments.Closed, 20},
+		{"IOC cancelled", market(IOC), instruments.Cancelled, 0},
+		{"FOK cancelled", market(FOK), instruments.Cancelled, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			om.Add(tt.order)
+
+			if tt.order.Status != tt.wantStatus {
+				t.Errorf("OrderManager.Add() status = %v, want %v", tt.order.Status, tt.wantStatus)
+			}
+			if tt.order.Filled() != tt.wantFilled {
+				t.Errorf("OrderManager.Add() filled = %v, want %v", tt.order.Filled(), tt.wantFilled)
+			}
+		})
+	}
+}
+
+func TestOrderManager_Match_expiry(t *testing.T) {
+	var day = func(d, hour, min int) time.Time {
+		return time.Date(2017, 8, d, hour, min, 0, 0, time.UTC)
+	}
+	var limit = func(placed time.Time) *Order {
+		return NewOrder("AAPL", true, instruments.Limit, instruments.NewPrice(9), 10, placed)
+	}
+	tests := []struct {
+		name    string
+		order   *Order
+		open    time.Time
+		expired time.Time
+	}{
+		{"DAY", limit(day(14, 9, 30)).WithTIF(DAY), day(14, 15, 59), day(14, 16, 0)},
+		{"DAY after close", limit(day(14, 16, 30)).WithTIF(DAY), day(15, 10, 0), day(15, 16, 0)},
+		{"GTD", limit(day(14, 9, 30)).GoodTill(day(16, 12, 0)), day(16, 11, 59), day(16, 12, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			om := mockOrderManager(1000)
+			gtc := limit(tt.order.Timestamp())
+			om.Add(gtc)
+			om.Add(tt.order)
+
+			var quote = mockQuote("AAPL", 9.95, 10.00)
+			quote.Timestamp = tt.open
+			om.Match(quote)
+			if tt.order.Status != instruments.Open {
+				t.Errorf("OrderManager.Match() status at %v = %v, want %v", tt.open, tt.order.Status, instruments.Open)
+			}
+			quote.Timestamp = tt.expired
+			om.Match(quote)
+			if tt.order.Status != instruments.Cancelled {
+				t.Errorf("OrderManager.Match() status at %v = %v, want %v", tt.expired, tt.order.Status, instruments.Cancelled)
+			}
+			if events := om.log.OrderEvents(); len(events) != 1 || events[0].Action != output.Expire {
+				t.Errorf("OrderManager.Match() audit log = %v, want one %v", events, output.Expire)
+			}
+			if gtc.Status != instruments.Open {
+				t.Errorf("OrderManager.Match() GTC status = %v, want %v", gtc.Status, instruments.Open)
+			}
+		})
+	}
+}
+
+func Test_sessionClose(t *testing.T) {
+	tests := []struct {
+		name  string
+		close string
+		want  time.Duration
+	}{
+		{"default", "", 16 * time.Hour},
+		{"configured", "13:30", 13*time.Hour + 30*time.Minute},
+		{"invalid", "1pm", 16 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c config.Config
+			c.Simulation.SessionClose = tt.close
+			if got := sessionClose(c); got != tt.want {
+				t.Errorf("sessionClose() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"time"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/instruments"
+)
+
+// warmUp is the period at the start of a simulation during which
+// algorithms are primed: quotes and bars are passed to them,
+// but their orders are suppressed and equity is not recorded.
+type warmUp struct {
+	period time.Duration
+	bars   int
+
+	start  time.Time
+	closed map[string]int
+	done   bool
+}
+
+func newWarmUp(c config.Config) warmUp {
+	return warmUp{period: c.Simulation.WarmUp, bars: c.Simulation.WarmUpBars}
+}
+
+// warming returns true if a quote at timestamp falls in the warm-up period,
+// which starts at the first quote. Once it returns false, it always will.
+func (w *warmUp) warming(timestamp time.Time) bool {
+	if w.done {
+		return false
+	}
+	if w.start.IsZero() {
+		w.start = timestamp
+	}
+	if timestamp.Before(w.start.Add(w.period)) || w.most() < w.bars {
+		return true
+	}
+	w.done = true
+	return false
+}
+
+// count bars closed during the warm-up period.
+func (w *warmUp) count(closed []Bar) {
+	if w.closed == nil {
+		w.closed = make(map[string]int)
+	}
+	for _, bar := range closed {
+		w.closed[bar.Name]++
+	}
+}
+
+// most returns the greatest number of bars closed of any one security.
+func (w *warmUp) most() int {
+	var max int
+	for _, n := range w.closed {
+		if n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// prime passes a quote to every algorithm during the warm-up period,
+// discarding any orders they create.
+func (sim *Simulation) prime(quote instruments.Quote) {
+	for _, algo := range sim.algos {
+		algo.Buy(quote)
+	}
+	if sim.barBuilder != nil {
+		sim.onBars(sim.barBuilder.Update(quote))
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2018 Jake Schurch
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package goat
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/jakeschurch/goat/internal/config"
+	"github.com/jakeschurch/instruments"
+)
+
+// primedAlgo counts the quotes passed to it, and buys a share on each.
+type primedAlgo struct {
+	quotes int
+}
+
+func (algo *primedAlgo) Buy(quote instruments.Quote) (*Order, bool) {
+	algo.quotes++
+	return FillOrder(quote, quote.Ask.Price, 1, true, instruments.Market), true
+}
+
+func (algo *primedAlgo) Sell(quote instruments.Quote, holding *instruments.Holding) (*Order, bool) {
+	return nil, false
+}
+
+func TestSimulation_warmUp(t *testing.T) {
+	tests := []struct {
+		name       string
+		warmUp     time.Duration
+		warmUpBars int
+		want       int
+	}{
+		{"none", 0, 0, 6},
+		{"duration", 2 * time.Minute, 0, 4},
+		{"bars", 0, 2, 2},
+		{"duration and bars", 5 * time.Minute, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conf config.Config
+			conf.Backtest.StartCashAmt = 1000
+			conf.Simulation.EquityQuotes = 1
+			conf.Simulation.WarmUp, conf.Simulation.WarmUpBars = tt.warmUp, tt.warmUpBars
+			conf.Simulation.BarType, conf.Simulation.BarSize = "tick", 2
+
+			algo := new(primedAlgo)
+			sim := NewSim(conf, algo)
+			for i := 0; i < 6; i++ {
+				q := mockQuote("AAPL", 10, 10.02)
+				q.Timestamp = q.Timestamp.Add(time.Duration(i) * time.Minute)
+				sim.process(&q)
+			}
+
+			if algo.quotes != 6 {
+				t.Errorf("Algorithm.Buy() called with %d quotes, want 6", algo.quotes)
+			}
+			var volume instruments.Volume
+			if list, err := sim.port.Holdings.Get("AAPL"); err == nil {
+				volume = list.Volume
+			}
+			if got := int(volume); got != tt.want {
+				t.Errorf("Simulation.process() bought %d shares, want %d", got, tt.want)
+			}
+			if got := len(sim.perfLog.EquityCurve()); got != tt.want {
+				t.Errorf("Simulation.process() equity samples = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulation_warmUp_ignored(t *testing.T) {
+	var conf config.Config
+	conf.Backtest.StartCashAmt = 1000
+	conf.Backtest.IgnoreSecurities = []string{"MSFT"}
+	conf.Simulation.WarmUp = 2 * time.Minute
+	conf.Benchmark.Use, conf.Benchmark.Symbol = true, "SPY"
+
+	var quotes = make([]*instruments.Quote, 0, 7)
+	ignored := mockQuote("MSFT", 72.5, 72.51)
+	ignored.Timestamp = ignored.Timestamp.Add(-time.Hour)
+	quotes = append(quotes, &ignored)
+	for i := 0; i < 6; i++ {
+		q := mockQuote("AAPL", 10, 10.02)
+		q.Timestamp = q.Timestamp.Add(time.Duration(i) * time.Minute)
+		quotes = append(quotes, &q)
+	}
+
+	algo := new(primedAlgo)
+	sim := NewSim(conf, algo)
+	sim.SetSink(WriterSink(ioutil.Discard))
+	if err := sim.RunSources(NewSliceSource(quotes...)); err != nil {
+		t.Fatalf("Simulation.RunSources() error = %v", err)
+	}
+	// The warm-up period starts at the first quote that is not ignored.
+	if got := len(sim.perfLog.Lots()); got != 4 {
+		t.Errorf("Simulation.RunSources() sold %d lots, want the 4 bought after warming up", got)
+	}
+}